@@ -3,44 +3,244 @@ package createDatabase
 import (
 	"database/sql"
 	"fmt"
+	"strings"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
 	_ "modernc.org/sqlite"
 )
 
-func CreateDatabase() {
-	db, err := sql.Open("sqlite", "scheduler.db")
-	if err != nil {
-		fmt.Println(err)
-		return
-	}
-	defer db.Close()
-
-	// Создание таблицы scheduler
-	createTableSQL := `
-	CREATE TABLE IF NOT EXISTS scheduler (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		date TEXT,
-		title TEXT,
-		comment TEXT,
-		repeat TEXT
-	);
-	`
-
-	_, err = db.Exec(createTableSQL)
+// Dialect — вид базы данных
+type Dialect string
+
+const (
+	SQLite   Dialect = "sqlite"
+	Postgres Dialect = "postgres"
+)
+
+// Database — подключение к базе вместе с её видом
+type Database struct {
+	*sql.DB
+	Dialect Dialect
+}
+
+// IsPostgresDSN сообщает, что dsn — адрес базы PostgreSQL вида postgres://...
+func IsPostgresDSN(dsn string) bool {
+	return strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://")
+}
+
+// Open открывает базу PostgreSQL, если dsn — её адрес, иначе базу SQLite
+// в файле dsn. Файл SQLite создаётся, если его нет; таблицы создаёт Migrate.
+func Open(dsn string) (*Database, error) {
+	driver, dialect := "sqlite", SQLite
+	if IsPostgresDSN(dsn) {
+		driver, dialect = "pgx", Postgres
+	} else {
+		dsn = "file:" + dsn + "?_pragma=busy_timeout(5000)"
+	}
+	db, err := sql.Open(driver, dsn)
 	if err != nil {
-		fmt.Println("Error creating table:", err)
-		return
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Database{DB: db, Dialect: dialect}, nil
+}
+
+// param возвращает n-й параметр запроса, начиная с 1
+func (d Dialect) param(n int) string {
+	if d == Postgres {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+// migrations — версии схемы базы по порядку. Уже выпущенные миграции
+// не меняются: изменения схемы добавляются новой миграцией в конец списка.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create scheduler",
+		Up: func(tx *sql.Tx, d Dialect) error {
+			id := "INTEGER PRIMARY KEY AUTOINCREMENT"
+			if d == Postgres {
+				id = "BIGSERIAL PRIMARY KEY"
+			}
+			// IF NOT EXISTS: базы, созданные до появления миграций,
+			// уже содержат таблицу
+			return execAll(tx, `
+			CREATE TABLE IF NOT EXISTS scheduler (
+				id `+id+`,
+				date TEXT,
+				title TEXT,
+				comment TEXT,
+				repeat TEXT
+			)`,
+				`CREATE INDEX IF NOT EXISTS idx_date ON scheduler(date)`)
+		},
+		Down: func(tx *sql.Tx, d Dialect) error {
+			return execAll(tx, `DROP TABLE scheduler`)
+		},
+	},
+	{
+		Version: 2,
+		Name:    "recurrence columns",
+		Up: func(tx *sql.Tx, d Dialect) error {
+			return addColumns(tx, d, "scheduler", recurrenceColumns)
+		},
+		Down: func(tx *sql.Tx, d Dialect) error {
+			return dropColumns(tx, "scheduler", recurrenceColumns)
+		},
+	},
+	{
+		Version: 3,
+		Name:    "search index",
+		// В PostgreSQL поиск идёт по самой таблице через ILIKE, индекс не нужен
+		Up: func(tx *sql.Tx, d Dialect) error {
+			if d != SQLite {
+				return nil
+			}
+			return execAll(tx, createSearchIndexSQL,
+				`INSERT INTO scheduler_fts (scheduler_fts) VALUES ('rebuild')`)
+		},
+		Down: func(tx *sql.Tx, d Dialect) error {
+			if d != SQLite {
+				return nil
+			}
+			return execAll(tx, dropSearchIndexSQL)
+		},
+	},
+	{
+		Version: 4,
+		Name:    "series anchor",
+		Up: func(tx *sql.Tx, d Dialect) error {
+			return addColumns(tx, d, "scheduler", anchorColumns)
+		},
+		Down: func(tx *sql.Tx, d Dialect) error {
+			return dropColumns(tx, "scheduler", anchorColumns)
+		},
+	},
+}
+
+// Колонки правил повторения, добавленные в таблицу scheduler после первой версии схемы
+var recurrenceColumns = []column{
+	{"until", "TEXT NOT NULL DEFAULT ''"},
+	{"max_count", "INTEGER NOT NULL DEFAULT 0"},
+	{"done_count", "INTEGER NOT NULL DEFAULT 0"},
+	{"exdates", "TEXT NOT NULL DEFAULT ''"},
+	{"shift", "TEXT NOT NULL DEFAULT ''"},
+	{"time", "TEXT NOT NULL DEFAULT ''"},
+	{"tz", "TEXT NOT NULL DEFAULT ''"},
+	{"mode", "TEXT NOT NULL DEFAULT ''"},
+	{"overflow", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// Первая дата серии повторений, от которой отсчитывается расписание.
+// У задач, созданных до её появления, она пустая и совпадает с date.
+var anchorColumns = []column{
+	{"anchor", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// Полнотекстовый индекс по заголовку и комментарию. Токенизатор trigram
+// позволяет искать любые подстроки длиной от трёх символов. Индекс
+// с внешним содержимым читает title и comment из scheduler по rowid = id,
+// а триггеры обновляют его при любом изменении задач, в том числе
+// в обход сервера. Для удаления строки из такого индекса FTS5 нужны
+// её старые значения.
+const createSearchIndexSQL = `
+CREATE VIRTUAL TABLE scheduler_fts USING fts5(title, comment, content = 'scheduler', content_rowid = 'id', tokenize = 'trigram');
+
+CREATE TRIGGER scheduler_fts_insert AFTER INSERT ON scheduler BEGIN
+	INSERT INTO scheduler_fts (rowid, title, comment) VALUES (new.id, new.title, new.comment);
+END;
+
+CREATE TRIGGER scheduler_fts_delete AFTER DELETE ON scheduler BEGIN
+	INSERT INTO scheduler_fts (scheduler_fts, rowid, title, comment) VALUES ('delete', old.id, old.title, old.comment);
+END;
+
+CREATE TRIGGER scheduler_fts_update AFTER UPDATE OF title, comment ON scheduler BEGIN
+	INSERT INTO scheduler_fts (scheduler_fts, rowid, title, comment) VALUES ('delete', old.id, old.title, old.comment);
+	INSERT INTO scheduler_fts (rowid, title, comment) VALUES (new.id, new.title, new.comment);
+END;
+`
+
+const dropSearchIndexSQL = `
+DROP TRIGGER scheduler_fts_insert;
+DROP TRIGGER scheduler_fts_delete;
+DROP TRIGGER scheduler_fts_update;
+DROP TABLE scheduler_fts;
+`
+
+type column struct {
+	name       string
+	definition string
+}
+
+// execAll выполняет запросы по очереди
+func execAll(tx *sql.Tx, queries ...string) error {
+	for _, query := range queries {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	// Создание индекса по полю date
-	createIndexSQL := `
-	CREATE INDEX IF NOT EXISTS idx_date ON scheduler(date);
-	`
+// addColumns добавляет в таблицу недостающие колонки. Колонки, которые
+// уже есть (их добавляли в базу до появления миграций), пропускаются.
+func addColumns(tx *sql.Tx, d Dialect, table string, columns []column) error {
+	if d == Postgres {
+		for _, c := range columns {
+			_, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s", table, c.name, c.definition))
+			if err != nil {
+				return fmt.Errorf("adding column %s: %w", c.name, err)
+			}
+		}
+		return nil
+	}
 
-	_, err = db.Exec(createIndexSQL)
+	existing, err := tableColumns(tx, table)
 	if err != nil {
-		fmt.Println("Error creating index:", err)
-		return
+		return err
+	}
+	for _, c := range columns {
+		if existing[c.name] {
+			continue
+		}
+		_, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, c.name, c.definition))
+		if err != nil {
+			return fmt.Errorf("adding column %s: %w", c.name, err)
+		}
+	}
+	return nil
+}
+
+// dropColumns удаляет колонки из таблицы в обратном порядке
+func dropColumns(tx *sql.Tx, table string, columns []column) error {
+	for i := len(columns) - 1; i >= 0; i-- {
+		_, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, columns[i].name))
+		if err != nil {
+			return fmt.Errorf("dropping column %s: %w", columns[i].name, err)
+		}
 	}
+	return nil
+}
 
-	fmt.Println("Table and index created successfully")
+// tableColumns возвращает имена колонок таблицы SQLite
+func tableColumns(tx *sql.Tx, table string) (map[string]bool, error) {
+	rows, err := tx.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		existing[strings.ToLower(name)] = true
+	}
+	return existing, rows.Err()
 }
@@ -0,0 +1,110 @@
+package createDatabase
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Migration — шаг изменения схемы базы. Up переводит схему из версии
+// Version-1 в Version, Down возвращает её обратно. Каждый шаг выполняется
+// в отдельной транзакции вместе с записью в schema_version.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *sql.Tx, d Dialect) error
+	Down    func(tx *sql.Tx, d Dialect) error
+}
+
+// Таблица применённых миграций. Текущая версия схемы — наибольшая версия в ней.
+const createVersionTableSQL = `
+CREATE TABLE IF NOT EXISTS schema_version (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at TEXT NOT NULL
+);
+`
+
+// Latest возвращает последнюю версию схемы
+func Latest() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// Version возвращает текущую версию схемы базы, 0 — пустая база
+func Version(db *Database) (int, error) {
+	if _, err := db.Exec(createVersionTableSQL); err != nil {
+		return 0, err
+	}
+	var version int
+	err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version)
+	return version, err
+}
+
+// Migrate применяет к базе все новые миграции
+func Migrate(db *Database) error {
+	return MigrateTo(db, Latest())
+}
+
+// MigrateTo переводит схему базы в версию target: применяет миграции вверх
+// или откатывает их вниз. target 0 удаляет все таблицы.
+func MigrateTo(db *Database, target int) error {
+	if target < 0 || target > Latest() {
+		return fmt.Errorf("unknown schema version: %d", target)
+	}
+	current, err := Version(db)
+	if err != nil {
+		return err
+	}
+	if current > Latest() {
+		return fmt.Errorf("database schema version %d is newer than supported %d", current, Latest())
+	}
+
+	for _, m := range migrations {
+		if m.Version > current && m.Version <= target {
+			if err := apply(db, m, true); err != nil {
+				return err
+			}
+		}
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.Version <= current && m.Version > target {
+			if err := apply(db, m, false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// apply выполняет миграцию вверх (up) или вниз и записывает это в schema_version
+func apply(db *Database, m Migration, up bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	step, direction := m.Up, "up"
+	if !up {
+		step, direction = m.Down, "down"
+	}
+	if err := step(tx, db.Dialect); err != nil {
+		return fmt.Errorf("migration %d (%s) %s: %w", m.Version, m.Name, direction, err)
+	}
+	if up {
+		d := db.Dialect
+		_, err = tx.Exec("INSERT INTO schema_version (version, name, applied_at) VALUES ("+d.param(1)+", "+d.param(2)+", "+d.param(3)+")",
+			m.Version, m.Name, time.Now().UTC().Format(time.RFC3339))
+	} else {
+		_, err = tx.Exec("DELETE FROM schema_version WHERE version = "+db.Dialect.param(1), m.Version)
+	}
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	fmt.Printf("Migration %d (%s) %s\n", m.Version, m.Name, direction)
+	return nil
+}
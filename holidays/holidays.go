@@ -0,0 +1,86 @@
+package holidays
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Calendar — производственный календарь: выходные по субботам и воскресеньям
+// плюс праздники, загруженные из файла.
+//
+// Формат файла: одна дата на строку, пустые строки и текст после "#"
+// пропускаются. Дата вида 20240102 задаёт праздник в конкретный год,
+// а вида 0308 — праздник, который повторяется каждый год.
+type Calendar struct {
+	dates  map[string]bool
+	annual map[string]bool
+}
+
+// New возвращает календарь без праздников
+func New() *Calendar {
+	return &Calendar{dates: make(map[string]bool), annual: make(map[string]bool)}
+}
+
+// Load загружает календарь праздников из файла
+func Load(path string) (*Calendar, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	cal := New()
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if err := cal.Add(line); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNumber, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return cal, nil
+}
+
+// Add добавляет праздник в формате 20060102 или ежегодный в формате 0102
+func (c *Calendar) Add(date string) error {
+	switch len(date) {
+	case len("20060102"):
+		if _, err := time.Parse("20060102", date); err != nil {
+			return fmt.Errorf("invalid holiday date: %s", date)
+		}
+		c.dates[date] = true
+	case len("0102"):
+		// Год 2024 високосный, поэтому 0229 тоже допустимо
+		if _, err := time.Parse("20060102", "2024"+date); err != nil {
+			return fmt.Errorf("invalid holiday date: %s", date)
+		}
+		c.annual[date] = true
+	default:
+		return fmt.Errorf("invalid holiday date: %s", date)
+	}
+	return nil
+}
+
+// IsHoliday сообщает, является ли день праздником
+func (c *Calendar) IsHoliday(day time.Time) bool {
+	return c.dates[day.Format("20060102")] || c.annual[day.Format("0102")]
+}
+
+// IsWorkday сообщает, является ли день рабочим
+func (c *Calendar) IsWorkday(day time.Time) bool {
+	if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		return false
+	}
+	return !c.IsHoliday(day)
+}
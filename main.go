@@ -1,26 +1,21 @@
 package main
 
 import (
-	"database/sql"
-	"fmt"
-	"github.com/MirekKrassilnikov/go_final_project/createDatabase"
+	"errors"
+	"flag"
+	"github.com/MirekKrassilnikov/go_final_project/config"
+	"github.com/MirekKrassilnikov/go_final_project/holidays"
+	"github.com/MirekKrassilnikov/go_final_project/repeater"
 	"github.com/MirekKrassilnikov/go_final_project/server"
 	"log"
-	_ "modernc.org/sqlite"
 	"net/http"
 	"os"
-	"path/filepath"
+	"strconv"
+	_ "time/tzdata"
 )
 
-// Порт, на котором будет работать сервер
-const port = "7540"
-
-// Директория для сервирования файлов
-const webDir = "./web"
 const layout = "20060102"
 
-var db *sql.DB
-
 type Task struct {
 	Date    string `json:"date"`
 	Title   string `json:"title"`
@@ -29,41 +24,62 @@ type Task struct {
 }
 
 func main() {
-	db, err := sql.Open("sqlite", "scheduler.db")
-	if err != nil {
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	} else if err != nil {
 		log.Fatal(err)
 	}
-	defer db.Close()
-	appPath, err := os.Executable()
-	if err != nil {
-		log.Fatal(err)
+
+	// go_final_project [флаги] migrate ... — управление схемой базы без запуска сервера
+	if len(args) > 0 && args[0] == "migrate" {
+		if cfg.InMemory() {
+			log.Fatal("in-memory storage has no schema to migrate")
+		}
+		if err := runMigrate(cfg.Database(), args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
-	dbFile := filepath.Join(filepath.Dir(appPath), "scheduler.db")
-	_, err = os.Stat(dbFile)
 
-	var install bool
-	if err != nil {
-		install = true
+	// Производственный календарь для правил по рабочим дням
+	if cfg.Holidays != "" {
+		cal, err := holidays.Load(cfg.Holidays)
+		if err != nil {
+			log.Fatal(err)
+		}
+		repeater.SetCalendar(cal)
 	}
 
-	if install {
-		createDatabase.CreateDatabase()
-	} else {
-		fmt.Println("Database already exists")
+	if !cfg.InMemory() {
+		if err := migrateDatabase(cfg.Database()); err != nil {
+			log.Fatal(err)
+		}
 	}
 
-	// Создаем файловый сервер для директории web
-	fs := http.FileServer(http.Dir(webDir))
-	// Настраиваем обработчик для всех запросов
-	http.Handle("/", fs)
-	http.HandleFunc("/api/task", server.TaskHandler)
-	http.HandleFunc("/api/tasks", server.GetAllTasksHandler)
-	http.HandleFunc("/api/nextdate", server.ApiNextDateHandler)
-	http.HandleFunc("/api/task/done", server.MarkAsDone)
+	// Одно подключение к базе на всё время работы сервера
+	store, err := openStore(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+
 	// Запускаем сервер на указанном порту
-	log.Printf("Starting server on :%s\n", port)
-	err = http.ListenAndServe(":"+port, nil)
+	log.Printf("Starting server on :%d\n", cfg.Port)
+	err = http.ListenAndServe(":"+strconv.Itoa(cfg.Port), server.NewHandler(store, cfg.WebDir, cfg.Password))
 	if err != nil {
 		log.Fatalf("Server failed: %v", err)
 	}
 }
+
+// openStore открывает хранилище задач: в памяти или PostgreSQL, если задан
+// адрес базы, иначе файл SQLite
+func openStore(cfg config.Config) (server.TaskStore, error) {
+	if cfg.InMemory() {
+		return server.NewMemoryStore(), nil
+	}
+	if cfg.DSN != "" {
+		return server.OpenPostgres(cfg.DSN)
+	}
+	return server.OpenSQLite(cfg.DBFile)
+}
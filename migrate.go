@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/MirekKrassilnikov/go_final_project/createDatabase"
+)
+
+const migrateUsage = `usage: go_final_project [flags] migrate [up | down [steps] | to version | status]`
+
+// migrateDatabase обновляет схему базы до последней версии перед запуском
+// сервера. database — адрес базы PostgreSQL или файл SQLite.
+func migrateDatabase(database string) error {
+	db, err := createDatabase.Open(database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return createDatabase.Migrate(db)
+}
+
+// runMigrate выполняет команду migrate для базы database:
+//
+//	up              — применить все новые миграции (по умолчанию)
+//	down [steps]    — откатить одну или steps последних миграций
+//	to version      — перейти к версии схемы version
+//	status          — показать текущую и последнюю версии
+func runMigrate(database string, args []string) error {
+	db, err := createDatabase.Open(database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	command := "up"
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+	current, err := createDatabase.Version(db)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case command == "up" && len(args) == 0:
+		return createDatabase.Migrate(db)
+	case command == "down" && len(args) <= 1:
+		steps := 1
+		if len(args) == 1 {
+			steps, err = strconv.Atoi(args[0])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps: %s", args[0])
+			}
+		}
+		return createDatabase.MigrateTo(db, max(current-steps, 0))
+	case command == "to" && len(args) == 1:
+		version, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid version: %s", args[0])
+		}
+		return createDatabase.MigrateTo(db, version)
+	case command == "status" && len(args) == 0:
+		fmt.Printf("Schema version: %d (latest %d)\n", current, createDatabase.Latest())
+		return nil
+	}
+	return errors.New(migrateUsage)
+}
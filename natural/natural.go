@@ -0,0 +1,335 @@
+package natural
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MirekKrassilnikov/go_final_project/repeater"
+)
+
+// Парсер фраз на английском и русском языках, которые превращаются в дату
+// задачи и правило повторения, например:
+//
+//	"tomorrow", "next friday", "in 3 days", "28.01.2024"
+//	"every other tuesday", "last day of every month", "every 2 weeks"
+//	"завтра", "в пятницу", "через 3 дня", "по понедельникам и средам",
+//	"в последний день месяца", "каждый второй вторник", "15 числа каждого месяца"
+//
+// "через день" считается правилом "d 2", а не датой послезавтра.
+
+const layout = "20060102"
+
+// Result — распознанные дата задачи и правило повторения
+type Result struct {
+	Date   string `json:"date"`
+	Repeat string `json:"repeat,omitempty"`
+}
+
+// Дни недели: английские названия целиком и русские по основе слова,
+// чтобы подходили любые падежи ("пятница", "пятницу", "пятницам")
+var englishWeekdays = map[string]int{
+	"monday": 1, "mon": 1, "mondays": 1,
+	"tuesday": 2, "tue": 2, "tues": 2, "tuesdays": 2,
+	"wednesday": 3, "wed": 3, "wednesdays": 3,
+	"thursday": 4, "thu": 4, "thurs": 4, "thursdays": 4,
+	"friday": 5, "fri": 5, "fridays": 5,
+	"saturday": 6, "sat": 6, "saturdays": 6,
+	"sunday": 7, "sun": 7, "sundays": 7,
+}
+
+var russianWeekdayStems = []struct {
+	stem    string
+	weekday int
+}{
+	{"понедельник", 1},
+	{"вторник", 2},
+	{"сред", 3},
+	{"четверг", 4},
+	{"пятниц", 5},
+	{"суббот", 6},
+	{"воскресень", 7},
+}
+
+// Слова, которые соединяют дни недели в списке
+var listConnectors = map[string]bool{"and": true, "и": true, "&": true}
+
+var rruleWeekdays = []string{"", "MO", "TU", "WE", "TH", "FR", "SA", "SU"}
+
+// repeatPattern сопоставляет фразе правило повторения. repeat получает
+// подгруппы регулярного выражения и текущую дату и возвращает результат.
+type repeatPattern struct {
+	re     *regexp.Regexp
+	repeat func(m []string, today time.Time) (Result, bool)
+}
+
+var (
+	numberDays  = `(\d+) (?:days?|дн[яей]+|день)`
+	numberWeeks = `(\d+) (?:weeks?|недел[иья]|недель)`
+)
+
+var repeatPatterns = []repeatPattern{
+	{regexp.MustCompile(`^(?:every day|each day|daily|каждый день|ежедневно)$`), fixed("d 1")},
+	{regexp.MustCompile(`^(?:every other day|через день)$`), fixed("d 2")},
+	{regexp.MustCompile(`^(?:every|каждые) ` + numberDays + `$`), multiple("d", 1)},
+	{regexp.MustCompile(`^(?:every week|each week|weekly|каждую неделю|еженедельно|раз в неделю)$`), fixed("d 7")},
+	{regexp.MustCompile(`^(?:every other week|раз в две недели)$`), fixed("d 14")},
+	{regexp.MustCompile(`^(?:every|каждые) ` + numberWeeks + `$`), multiple("d", 7)},
+	{regexp.MustCompile(`^(?:every year|each year|yearly|annually|каждый год|ежегодно|раз в год)$`), fixed("y")},
+	{regexp.MustCompile(`^(?:every month|each month|monthly|каждый месяц|ежемесячно|раз в месяц)$`), sameMonthDay},
+	{regexp.MustCompile(`^(?:every weekday|on weekdays|weekdays|по будням|каждый будний день|в будние дни)$`), fixed("w 1,2,3,4,5")},
+	{regexp.MustCompile(`^(?:every weekend|on weekends|по выходным|в выходные)$`), fixed("w 6,7")},
+	{regexp.MustCompile(`^(?:(?:on )?the )?last day of (?:every|each|the) month$`), monthly("m -1")},
+	{regexp.MustCompile(`^(?:в )?последний день (?:каждого )?месяца$`), monthly("m -1")},
+	{regexp.MustCompile(`^(?:on )?(?:the )?(\d{1,2})(?:st|nd|rd|th)? (?:day )?of (?:every|each) month$`), monthDay},
+	{regexp.MustCompile(`^every month on the (\d{1,2})(?:st|nd|rd|th)?$`), monthDay},
+	{regexp.MustCompile(`^(?:каждое )?(\d{1,2})(?:-?е|-?го)? числ[оа](?: каждого месяца)?$`), monthDay},
+	{regexp.MustCompile(`^(?:every other|every second) (.+)$`), biweekly},
+	{regexp.MustCompile(`^кажд(?:ый|ую|ое) втор(?:ой|ую|ое) (.+)$`), biweekly},
+	{regexp.MustCompile(`^(?:every|each|каждый|каждую|каждое|по) (.+)$`), weekly},
+}
+
+// fixed возвращает правило без параметров с датой начала сегодня
+func fixed(repeat string) func([]string, time.Time) (Result, bool) {
+	return func(_ []string, today time.Time) (Result, bool) {
+		return Result{Date: today.Format(layout), Repeat: repeat}, true
+	}
+}
+
+// multiple возвращает правило "code N*factor" с датой начала сегодня
+func multiple(code string, factor int) func([]string, time.Time) (Result, bool) {
+	return func(m []string, today time.Time) (Result, bool) {
+		n, err := strconv.Atoi(m[1])
+		if err != nil || n < 1 {
+			return Result{}, false
+		}
+		return Result{Date: today.Format(layout), Repeat: fmt.Sprintf("%s %d", code, n*factor)}, true
+	}
+}
+
+func sameMonthDay(_ []string, today time.Time) (Result, bool) {
+	return Result{Date: today.Format(layout), Repeat: fmt.Sprintf("m %d", today.Day())}, true
+}
+
+func monthDay(m []string, today time.Time) (Result, bool) {
+	day, err := strconv.Atoi(m[1])
+	if err != nil || day < 1 || day > 31 {
+		return Result{}, false
+	}
+	return monthly(fmt.Sprintf("m %d", day))(m, today)
+}
+
+// monthly возвращает правило "m" с датой начала в ближайший подходящий
+// день, начиная с сегодняшнего
+func monthly(repeat string) func([]string, time.Time) (Result, bool) {
+	return func(_ []string, today time.Time) (Result, bool) {
+		rule, err := repeater.Parse(repeat)
+		if err != nil {
+			return Result{}, false
+		}
+		yesterday := today.AddDate(0, 0, -1)
+		first := rule.From(yesterday).Next(yesterday)
+		if first.IsZero() {
+			return Result{}, false
+		}
+		return Result{Date: first.Format(layout), Repeat: repeat}, true
+	}
+}
+
+// weekly разбирает список дней недели: "monday and friday", "понедельникам и средам"
+func weekly(m []string, today time.Time) (Result, bool) {
+	weekdays, ok := parseWeekdayList(m[1])
+	if !ok {
+		return Result{}, false
+	}
+	parts := make([]string, 0, len(weekdays))
+	for _, wd := range weekdays {
+		parts = append(parts, strconv.Itoa(wd))
+	}
+	return Result{
+		Date:   nextWeekday(today, weekdays, true).Format(layout),
+		Repeat: "w " + strings.Join(parts, ","),
+	}, true
+}
+
+// biweekly разбирает "every other tuesday": раз в две недели, начиная
+// с ближайшего подходящего дня
+func biweekly(m []string, today time.Time) (Result, bool) {
+	weekdays, ok := parseWeekdayList(m[1])
+	if !ok {
+		return Result{}, false
+	}
+	days := make([]string, 0, len(weekdays))
+	for _, wd := range weekdays {
+		days = append(days, rruleWeekdays[wd])
+	}
+	return Result{
+		Date:   nextWeekday(today, weekdays, true).Format(layout),
+		Repeat: "FREQ=WEEKLY;INTERVAL=2;BYDAY=" + strings.Join(days, ","),
+	}, true
+}
+
+// weekdayOf возвращает номер дня недели (1 — понедельник, 7 — воскресенье)
+func weekdayOf(word string) (int, bool) {
+	if wd, ok := englishWeekdays[word]; ok {
+		return wd, true
+	}
+	for _, item := range russianWeekdayStems {
+		if strings.HasPrefix(word, item.stem) {
+			return item.weekday, true
+		}
+	}
+	return 0, false
+}
+
+// parseWeekdayList разбирает список дней недели через запятую, "and" или "и".
+// Возвращает false, если в списке есть что-то кроме дней недели.
+func parseWeekdayList(s string) ([]int, bool) {
+	seen := make(map[int]bool)
+	var weekdays []int
+	for _, word := range strings.Fields(strings.ReplaceAll(s, ",", " ")) {
+		if listConnectors[word] {
+			continue
+		}
+		wd, ok := weekdayOf(word)
+		if !ok {
+			return nil, false
+		}
+		if !seen[wd] {
+			seen[wd] = true
+			weekdays = append(weekdays, wd)
+		}
+	}
+	if len(weekdays) == 0 {
+		return nil, false
+	}
+	return weekdays, true
+}
+
+// nextWeekday возвращает ближайший день из weekdays после today,
+// а при includeToday — начиная с today
+func nextWeekday(today time.Time, weekdays []int, includeToday bool) time.Time {
+	first := 1
+	if includeToday {
+		first = 0
+	}
+	for i := first; i <= 7; i++ {
+		day := today.AddDate(0, 0, i)
+		iso := int(day.Weekday())
+		if iso == 0 {
+			iso = 7
+		}
+		for _, wd := range weekdays {
+			if wd == iso {
+				return day
+			}
+		}
+	}
+	return today
+}
+
+var (
+	weekdayDateRe = regexp.MustCompile(`^(?:next |this |on |(?:в|во) (?:следующ\S+ |эт\S+ )?)?(\S+)$`)
+	inAmountRe    = regexp.MustCompile(`^(?:in|через) (?:(\d+|a|an|one) )?(\S+)$`)
+	dottedDateRe  = regexp.MustCompile(`^\d{2}\.\d{2}\.\d{4}$`)
+	compactDateRe = regexp.MustCompile(`^\d{8}$`)
+)
+
+// parseDate распознаёт фразу, которая задаёт только дату
+func parseDate(text string, today time.Time) (time.Time, bool) {
+	switch text {
+	case "today", "сегодня":
+		return today, true
+	case "tomorrow", "завтра":
+		return today.AddDate(0, 0, 1), true
+	case "day after tomorrow", "the day after tomorrow", "послезавтра":
+		return today.AddDate(0, 0, 2), true
+	}
+
+	if dottedDateRe.MatchString(text) {
+		date, err := time.Parse("02.01.2006", text)
+		return date, err == nil
+	}
+	if compactDateRe.MatchString(text) {
+		date, err := time.Parse(layout, text)
+		return date, err == nil
+	}
+
+	if m := weekdayDateRe.FindStringSubmatch(text); m != nil {
+		if wd, ok := weekdayOf(m[1]); ok {
+			return nextWeekday(today, []int{wd}, false), true
+		}
+	}
+
+	if m := inAmountRe.FindStringSubmatch(text); m != nil {
+		n := 1
+		if m[1] != "" && m[1] != "a" && m[1] != "an" && m[1] != "one" {
+			var err error
+			n, err = strconv.Atoi(m[1])
+			if err != nil {
+				return time.Time{}, false
+			}
+		}
+		return addUnits(today, n, m[2])
+	}
+	return time.Time{}, false
+}
+
+// addUnits прибавляет к дате n дней, недель, месяцев или лет по названию единицы
+func addUnits(today time.Time, n int, unit string) (time.Time, bool) {
+	switch {
+	case unit == "day" || unit == "days" || unit == "день" || strings.HasPrefix(unit, "дн"):
+		return today.AddDate(0, 0, n), true
+	case strings.HasPrefix(unit, "week") || strings.HasPrefix(unit, "недел"):
+		return today.AddDate(0, 0, 7*n), true
+	case strings.HasPrefix(unit, "month") || strings.HasPrefix(unit, "месяц"):
+		return today.AddDate(0, n, 0), true
+	case strings.HasPrefix(unit, "year") || strings.HasPrefix(unit, "год") || unit == "лет":
+		return today.AddDate(n, 0, 0), true
+	}
+	return time.Time{}, false
+}
+
+// normalize приводит фразу к нижнему регистру, убирает лишние пробелы
+// и знаки препинания, кроме точек в датах
+func normalize(text string) string {
+	text = strings.ToLower(strings.TrimSpace(text))
+	text = strings.ReplaceAll(text, "ё", "е")
+	text = strings.NewReplacer("!", " ", "?", " ", ";", " ").Replace(text)
+	text = strings.TrimSuffix(text, ".")
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// Parse распознаёт фразу text относительно даты now и возвращает дату задачи
+// и, если фраза задаёт повторение, правило в каноническом виде
+func Parse(text string, now time.Time) (Result, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	phrase := normalize(text)
+	if phrase == "" {
+		return Result{}, fmt.Errorf("empty phrase")
+	}
+
+	for _, p := range repeatPatterns {
+		m := p.re.FindStringSubmatch(phrase)
+		if m == nil {
+			continue
+		}
+		result, ok := p.repeat(m, today)
+		if !ok {
+			continue
+		}
+		rule, err := repeater.Parse(result.Repeat)
+		if err != nil {
+			return Result{}, fmt.Errorf("cannot parse phrase: %s", text)
+		}
+		result.Repeat = rule.String()
+		return result, nil
+	}
+
+	if date, ok := parseDate(phrase, today); ok {
+		return Result{Date: date.Format(layout)}, nil
+	}
+	return Result{}, fmt.Errorf("cannot parse phrase: %s", text)
+}
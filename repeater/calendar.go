@@ -0,0 +1,95 @@
+package repeater
+
+import (
+	"fmt"
+	"time"
+)
+
+// Максимальное число дней подряд, которые просматриваются в поисках рабочего дня
+const maxShiftDays = 31
+
+// Calendar сообщает, является ли день рабочим.
+// Используется правилом "b" и переносом дат с выходных.
+type Calendar interface {
+	IsWorkday(day time.Time) bool
+}
+
+// weekends — календарь по умолчанию: выходные только суббота и воскресенье
+type weekends struct{}
+
+func (weekends) IsWorkday(day time.Time) bool {
+	return day.Weekday() != time.Saturday && day.Weekday() != time.Sunday
+}
+
+var calendar Calendar = weekends{}
+
+// SetCalendar задаёт календарь рабочих дней. nil возвращает календарь
+// по умолчанию, в котором выходные только суббота и воскресенье.
+func SetCalendar(c Calendar) {
+	if c == nil {
+		c = weekends{}
+	}
+	calendar = c
+}
+
+// ShiftMode определяет, куда переносится дата, выпавшая на нерабочий день
+type ShiftMode string
+
+const (
+	// NoShift — дата не переносится
+	NoShift ShiftMode = ""
+	// ShiftNext — дата переносится на ближайший следующий рабочий день
+	ShiftNext ShiftMode = "next"
+	// ShiftPrev — дата переносится на ближайший предыдущий рабочий день
+	ShiftPrev ShiftMode = "prev"
+)
+
+// ParseShift разбирает режим переноса дат
+func ParseShift(s string) (ShiftMode, error) {
+	switch mode := ShiftMode(s); mode {
+	case NoShift, ShiftNext, ShiftPrev:
+		return mode, nil
+	}
+	return NoShift, fmt.Errorf("invalid shift mode: %s", s)
+}
+
+// shiftDate переносит дату на рабочий день согласно режиму.
+// Нулевое время означает, что рабочий день не найден.
+func shiftDate(date time.Time, mode ShiftMode) time.Time {
+	step := 0
+	switch mode {
+	case ShiftNext:
+		step = 1
+	case ShiftPrev:
+		step = -1
+	default:
+		return date
+	}
+	for i := 0; i < maxShiftDays; i++ {
+		if calendar.IsWorkday(date) {
+			return date
+		}
+		date = date.AddDate(0, 0, step)
+	}
+	return time.Time{}
+}
+
+// addWorkdays возвращает дату, отстоящую от date на n рабочих дней вперёд.
+// Нулевое время означает, что рабочие дни не найдены.
+func addWorkdays(date time.Time, n int) time.Time {
+	for n > 0 {
+		found := false
+		for i := 0; i < maxShiftDays; i++ {
+			date = date.AddDate(0, 0, 1)
+			if calendar.IsWorkday(date) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return time.Time{}
+		}
+		n--
+	}
+	return date
+}
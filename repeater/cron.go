@@ -0,0 +1,209 @@
+package repeater
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Правила повторения в формате cron из пяти полей:
+// "минута час день_месяца месяц день_недели", например "30 9 * * 1-5".
+// Поля поддерживают "*", списки через запятую, диапазоны "a-b" и шаг "/n",
+// месяцы и дни недели можно записывать как JAN-DEC и SUN-SAT.
+// Также поддерживаются @yearly, @annually, @monthly, @weekly, @daily,
+// @midnight и @hourly.
+
+// Сколько лет вперёд просматривается в поисках подходящего момента
+const maxCronYears = 5
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var cronMonthNames = []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+
+var cronWeekdayNames = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+
+// cronField — множество допустимых значений поля и признак "*"
+type cronField struct {
+	values map[int]bool
+	any    bool
+}
+
+type cronSpec struct {
+	expr                                   string
+	minute, hour, monthDay, month, weekday cronField
+}
+
+// isCron сообщает, записано ли правило повторения в формате cron
+func isCron(repeat string) bool {
+	return strings.HasPrefix(repeat, "@") || len(strings.Fields(repeat)) == 5
+}
+
+// parseCron разбирает выражение cron или макрос вида @daily
+func parseCron(repeat string) (cronSpec, error) {
+	expr := strings.ToUpper(strings.Join(strings.Fields(repeat), " "))
+	fields := expr
+	if strings.HasPrefix(expr, "@") {
+		expr = strings.ToLower(expr)
+		macro, ok := cronMacros[expr]
+		if !ok {
+			return cronSpec{}, fmt.Errorf("unknown cron macro: %s", repeat)
+		}
+		fields = macro
+	}
+
+	parts := strings.Fields(fields)
+	if len(parts) != 5 {
+		return cronSpec{}, fmt.Errorf("cron expression must have 5 fields: %s", repeat)
+	}
+	spec := cronSpec{expr: expr}
+	var err error
+	if spec.minute, err = parseCronField(parts[0], 0, 59, nil); err != nil {
+		return cronSpec{}, err
+	}
+	if spec.hour, err = parseCronField(parts[1], 0, 23, nil); err != nil {
+		return cronSpec{}, err
+	}
+	if spec.monthDay, err = parseCronField(parts[2], 1, 31, nil); err != nil {
+		return cronSpec{}, err
+	}
+	if spec.month, err = parseCronField(parts[3], 1, 12, cronMonthNames); err != nil {
+		return cronSpec{}, err
+	}
+	// Воскресенье можно записать и как 0, и как 7
+	if spec.weekday, err = parseCronField(parts[4], 0, 7, cronWeekdayNames); err != nil {
+		return cronSpec{}, err
+	}
+	if spec.weekday.values[7] {
+		spec.weekday.values[0] = true
+	}
+	if !spec.firesOnSomeDay() {
+		return cronSpec{}, fmt.Errorf("cron days never occur in the given months: %s", repeat)
+	}
+	return spec, nil
+}
+
+// firesOnSomeDay сообщает, найдётся ли день, подходящий выражению.
+// Если день недели ограничен вместе с днём месяца, подойдёт любой день
+// с нужным днём недели (см. matchesDay), иначе хотя бы одно число
+// должно существовать хотя бы в одном из месяцев.
+func (c cronSpec) firesOnSomeDay() bool {
+	if c.monthDay.any || !c.weekday.any {
+		return true
+	}
+	days := make([]int, 0, len(c.monthDay.values))
+	for day := range c.monthDay.values {
+		days = append(days, day)
+	}
+	months := make(map[time.Month]bool, len(c.month.values))
+	for month := range c.month.values {
+		months[time.Month(month)] = true
+	}
+	return monthDaysExist(days, months)
+}
+
+// parseCronField разбирает одно поле cron. names — названия значений,
+// начиная с min (для месяцев и дней недели).
+func parseCronField(s string, min, max int, names []string) (cronField, error) {
+	field := cronField{values: make(map[int]bool), any: s == "*"}
+	for _, part := range strings.Split(s, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step < 1 {
+				return cronField{}, fmt.Errorf("invalid cron step: %s", part)
+			}
+		}
+
+		low, high := min, max
+		if rangePart != "*" {
+			fromStr, toStr, isRange := strings.Cut(rangePart, "-")
+			var err error
+			low, err = cronValue(fromStr, min, names)
+			if err != nil {
+				return cronField{}, err
+			}
+			high = low
+			if isRange {
+				high, err = cronValue(toStr, min, names)
+				if err != nil {
+					return cronField{}, err
+				}
+			} else if hasStep {
+				// "5/15" означает с 5 до конца диапазона с шагом 15
+				high = max
+			}
+		}
+		if low < min || high > max || low > high {
+			return cronField{}, fmt.Errorf("cron value out of range %d-%d: %s", min, max, part)
+		}
+		for v := low; v <= high; v += step {
+			field.values[v] = true
+		}
+	}
+	return field, nil
+}
+
+// cronValue разбирает число или название значения поля cron
+func cronValue(s string, min int, names []string) (int, error) {
+	for i, name := range names {
+		if strings.EqualFold(s, name) {
+			return min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cron value: %s", s)
+	}
+	return v, nil
+}
+
+// matchesDay проверяет день месяца и день недели. Как и в cron, если ограничены
+// оба поля, достаточно совпадения любого из них.
+func (c cronSpec) matchesDay(t time.Time) bool {
+	monthDay := c.monthDay.values[t.Day()]
+	weekday := c.weekday.values[int(t.Weekday())]
+	if c.monthDay.any || c.weekday.any {
+		return monthDay && weekday
+	}
+	return monthDay || weekday
+}
+
+// next возвращает первый подходящий момент строго после from по местным часам
+func (c cronSpec) next(from time.Time) time.Time {
+	loc := from.Location()
+	t := from.Truncate(time.Minute).Add(time.Minute)
+	limit := from.Year() + maxCronYears
+	for t.Year() <= limit {
+		year, month, day := t.Date()
+		var next time.Time
+		switch {
+		case !c.month.values[int(month)]:
+			next = time.Date(year, month+1, 1, 0, 0, 0, 0, loc)
+		case !c.matchesDay(t):
+			next = time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+		case !c.hour.values[t.Hour()]:
+			next = time.Date(year, month, day, t.Hour()+1, 0, 0, 0, loc)
+		case !c.minute.values[t.Minute()]:
+			next = t.Add(time.Minute)
+		default:
+			return t
+		}
+		// При переходе на летнее время time.Date может вернуть момент раньше t
+		if !next.After(t) {
+			next = t.Add(time.Minute)
+		}
+		t = next
+	}
+	return time.Time{}
+}
@@ -0,0 +1,483 @@
+package repeater
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Language — язык, на котором описывается правило повторения
+type Language string
+
+const (
+	// English — описание на английском, например "every 2 weeks on Tuesday"
+	English Language = "en"
+	// Russian — описание на русском, например "каждые 2 недели по вторникам"
+	Russian Language = "ru"
+)
+
+// ParseLanguage возвращает язык по коду вроде "ru" или "en-US".
+// Неизвестные языки описываются по-английски.
+func ParseLanguage(s string) Language {
+	code, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(s)), "-")
+	if Language(code) == Russian {
+		return Russian
+	}
+	return English
+}
+
+// Describe возвращает описание правила повторения на языке lang
+func Describe(repeat string, lang Language) (string, error) {
+	rule, err := Parse(repeat)
+	if err != nil {
+		return "", err
+	}
+	return rule.Describe(lang), nil
+}
+
+// unit — единица интервала: "каждый день", "каждые 2 дня", "every 3 days".
+// ru содержит форму для одного ("каждый день"), а также формы для 2–4 и 5–20.
+type unit struct {
+	en, enPlural string
+	ruEach       string
+	ru           [3]string
+}
+
+var (
+	unitDay     = unit{"day", "days", "каждый", [3]string{"день", "дня", "дней"}}
+	unitWorkday = unit{"workday", "workdays", "каждый", [3]string{"рабочий день", "рабочих дня", "рабочих дней"}}
+	unitHour    = unit{"hour", "hours", "каждый", [3]string{"час", "часа", "часов"}}
+	unitMinute  = unit{"minute", "minutes", "каждую", [3]string{"минуту", "минуты", "минут"}}
+	unitWeek    = unit{"week", "weeks", "каждую", [3]string{"неделю", "недели", "недель"}}
+	unitMonth   = unit{"month", "months", "каждый", [3]string{"месяц", "месяца", "месяцев"}}
+	unitYear    = unit{"year", "years", "каждый", [3]string{"год", "года", "лет"}}
+)
+
+var englishMonths = []string{"", "January", "February", "March", "April", "May", "June",
+	"July", "August", "September", "October", "November", "December"}
+
+// Названия месяцев в родительном ("26 января") и предложном ("в январе") падежах
+var (
+	russianMonthsGenitive = []string{"", "января", "февраля", "марта", "апреля", "мая", "июня",
+		"июля", "августа", "сентября", "октября", "ноября", "декабря"}
+	russianMonthsPrepositional = []string{"", "январе", "феврале", "марте", "апреле", "мае", "июне",
+		"июле", "августе", "сентябре", "октябре", "ноябре", "декабре"}
+)
+
+// Дни недели в порядке time.Weekday: с воскресенья
+var (
+	englishWeekdays = []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+	// "по понедельникам"
+	russianWeekdaysDative = []string{"воскресеньям", "понедельникам", "вторникам", "средам",
+		"четвергам", "пятницам", "субботам"}
+	// "в понедельник", "в среду"
+	russianWeekdaysAccusative = []string{"воскресенье", "понедельник", "вторник", "среду",
+		"четверг", "пятницу", "субботу"}
+)
+
+// Род дня недели для согласования порядковых числительных:
+// 0 — мужской, 1 — женский, 2 — средний
+var russianWeekdayGender = []int{2, 0, 0, 1, 0, 1, 1}
+
+// Порядковые числительные для "в первый вторник", "в последнюю пятницу"
+// по родам; индекс 1..5 — с начала месяца, -1 и -2 — с конца
+var russianOrdinals = map[int][3]string{
+	1:  {"первый", "первую", "первое"},
+	2:  {"второй", "вторую", "второе"},
+	3:  {"третий", "третью", "третье"},
+	4:  {"четвёртый", "четвёртую", "четвёртое"},
+	5:  {"пятый", "пятую", "пятое"},
+	-1: {"последний", "последнюю", "последнее"},
+	-2: {"предпоследний", "предпоследнюю", "предпоследнее"},
+}
+
+// Describe возвращает описание правила на языке lang, например
+// "on the 18th and the last day of every month" или
+// "18 числа и в последний день каждого месяца"
+func (r Rule) Describe(lang Language) string {
+	var s string
+	switch r.code {
+	case "d":
+		s = every(r.interval, unitDay, lang)
+	case "b":
+		s = every(r.interval, unitWorkday, lang)
+	case "h", "n":
+		u := unitHour
+		if r.code == "n" {
+			u = unitMinute
+		}
+		s = every(r.interval, u, lang)
+		if r.window {
+			s += " " + describeWindow(r.windowFrom, r.windowTo, lang)
+		}
+	case "y":
+		s = every(1, unitYear, lang)
+		if !r.start.IsZero() {
+			s += " " + describeDayOfYear(r.start, lang)
+		}
+	case "m":
+		s = describeMonthDays(r.days, r.months, lang)
+	case "w":
+		s = describeWeekdays(r.weekdays, lang)
+	case "rrule":
+		s = r.rrule.describe(lang)
+	case "cron":
+		if lang == Russian {
+			s = "по расписанию cron «" + r.cron.expr + "»"
+		} else {
+			s = `on cron schedule "` + r.cron.expr + `"`
+		}
+	}
+
+	if !r.until.IsZero() {
+		if lang == Russian {
+			s += " до " + r.until.Format("02.01.2006")
+		} else {
+			s += " until " + r.until.Format("02.01.2006")
+		}
+	}
+	if r.count > 0 {
+		s += ", " + describeTimes(r.count, lang)
+	}
+	switch r.shift {
+	case ShiftNext:
+		if lang == Russian {
+			s += ", с переносом на следующий рабочий день"
+		} else {
+			s += ", moved to the next workday"
+		}
+	case ShiftPrev:
+		if lang == Russian {
+			s += ", с переносом на предыдущий рабочий день"
+		} else {
+			s += ", moved to the previous workday"
+		}
+	}
+	return s
+}
+
+// every описывает интервал: "every day", "every 3 days", "каждые 3 дня"
+func every(n int, u unit, lang Language) string {
+	if lang == Russian {
+		if n == 1 {
+			return u.ruEach + " " + u.ru[0]
+		}
+		form := russianPlural(n, u.ru)
+		// "каждый 21 день", но "каждые 22 дня"
+		if form == u.ru[0] {
+			return u.ruEach + " " + strconv.Itoa(n) + " " + form
+		}
+		return "каждые " + strconv.Itoa(n) + " " + form
+	}
+	if n == 1 {
+		return "every " + u.en
+	}
+	return "every " + strconv.Itoa(n) + " " + u.enPlural
+}
+
+// russianPlural выбирает форму слова для числа n: 1 день, 2 дня, 5 дней
+func russianPlural(n int, forms [3]string) string {
+	if n < 0 {
+		n = -n
+	}
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return forms[0]
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 10 || n%100 >= 20):
+		return forms[1]
+	}
+	return forms[2]
+}
+
+// describeTimes описывает количество повторений: "5 times", "5 раз"
+func describeTimes(n int, lang Language) string {
+	if lang == Russian {
+		return strconv.Itoa(n) + " " + russianPlural(n, [3]string{"раз", "раза", "раз"})
+	}
+	switch n {
+	case 1:
+		return "once"
+	case 2:
+		return "twice"
+	}
+	return strconv.Itoa(n) + " times"
+}
+
+func describeWindow(from, to int, lang Language) string {
+	clock := func(m int) string { return fmt.Sprintf("%02d:%02d", m/60, m%60) }
+	if lang == Russian {
+		return "с " + clock(from) + " до " + clock(to)
+	}
+	return "from " + clock(from) + " to " + clock(to)
+}
+
+func describeDayOfYear(date time.Time, lang Language) string {
+	if lang == Russian {
+		return strconv.Itoa(date.Day()) + " " + russianMonthsGenitive[date.Month()]
+	}
+	return "on " + englishMonths[date.Month()] + " " + strconv.Itoa(date.Day())
+}
+
+// englishOrdinal возвращает "1st", "2nd", "3rd", "11th", "22nd"
+func englishOrdinal(n int) string {
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+// joinList соединяет элементы списка: "a, b and c", "a, b и c"
+func joinList(items []string, lang Language) string {
+	and := " and "
+	if lang == Russian {
+		and = " и "
+	}
+	if len(items) <= 1 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + and + items[len(items)-1]
+}
+
+// sortedDays возвращает дни месяца в порядке правила: сначала по возрастанию,
+// затем с конца месяца
+func sortedDays(days []int) []int {
+	var sorted []int
+	for _, part := range strings.Split(joinInts(days), ",") {
+		day, _ := strconv.Atoi(part)
+		sorted = append(sorted, day)
+	}
+	return sorted
+}
+
+// sortedMonths возвращает месяцы по порядку
+func sortedMonths(months map[time.Month]bool) []time.Month {
+	sorted := make([]time.Month, 0, len(months))
+	for month := range months {
+		sorted = append(sorted, month)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
+
+// describeMonthDay описывает один день месяца: "the 18th", "the last day",
+// "18 числа", "в последний день"
+func describeMonthDay(day int, lang Language) string {
+	if lang == Russian {
+		switch day {
+		case -1:
+			return "в последний день"
+		case -2:
+			return "в предпоследний день"
+		}
+		if day < 0 {
+			return "в " + strconv.Itoa(-day) + "-й день с конца"
+		}
+		return strconv.Itoa(day) + " числа"
+	}
+	switch day {
+	case -1:
+		return "the last day"
+	case -2:
+		return "the second-to-last day"
+	}
+	if day < 0 {
+		return "the " + englishOrdinal(-day) + " day from the end"
+	}
+	return "the " + englishOrdinal(day)
+}
+
+// describeMonths описывает месяцы правила: "of every month", "of January and March",
+// "каждого месяца", "в январе и марте"
+func describeMonths(months map[time.Month]bool, lang Language) string {
+	if len(months) == 0 {
+		if lang == Russian {
+			return "каждого месяца"
+		}
+		return "of every month"
+	}
+	names := make([]string, 0, len(months))
+	for _, month := range sortedMonths(months) {
+		if lang == Russian {
+			names = append(names, russianMonthsPrepositional[month])
+		} else {
+			names = append(names, englishMonths[month])
+		}
+	}
+	if lang == Russian {
+		return "в " + joinList(names, lang)
+	}
+	return "of " + joinList(names, lang)
+}
+
+func describeMonthDays(days []int, months map[time.Month]bool, lang Language) string {
+	items := make([]string, 0, len(days))
+	for _, day := range sortedDays(days) {
+		items = append(items, describeMonthDay(day, lang))
+	}
+	if lang == Russian {
+		return joinList(items, lang) + " " + describeMonths(months, lang)
+	}
+	return "on " + joinList(items, lang) + " " + describeMonths(months, lang)
+}
+
+// describeWeekdays описывает правило "w": "every Monday and Friday", "по понедельникам и пятницам"
+func describeWeekdays(weekdays map[time.Weekday]bool, lang Language) string {
+	workdays := len(weekdays) == 5 && !weekdays[time.Saturday] && !weekdays[time.Sunday]
+	weekend := len(weekdays) == 2 && weekdays[time.Saturday] && weekdays[time.Sunday]
+	switch {
+	case len(weekdays) == 7:
+		return every(1, unitDay, lang)
+	case workdays && lang == Russian:
+		return "по будним дням"
+	case workdays:
+		return "every weekday"
+	case weekend && lang == Russian:
+		return "по выходным"
+	case weekend:
+		return "every weekend"
+	}
+	if lang == Russian {
+		return "по " + joinList(weekdayNames(weekdays, russianWeekdaysDative), lang)
+	}
+	return "every " + joinList(weekdayNames(weekdays, englishWeekdays), lang)
+}
+
+// weekdayNames возвращает названия дней недели, начиная с понедельника
+func weekdayNames(weekdays map[time.Weekday]bool, names []string) []string {
+	var result []string
+	for i := 1; i <= 7; i++ {
+		weekday := time.Weekday(i % 7)
+		if weekdays[weekday] {
+			result = append(result, names[weekday])
+		}
+	}
+	return result
+}
+
+// describe описывает правило RRULE, например "every 2 weeks on Monday and Friday"
+// или "каждый месяц в последнюю пятницу"
+func (r rrule) describe(lang Language) string {
+	units := map[string]unit{"DAILY": unitDay, "WEEKLY": unitWeek, "MONTHLY": unitMonth, "YEARLY": unitYear}
+	s := every(r.interval, units[r.freq], lang)
+
+	if len(r.byMonth) > 0 {
+		months := describeMonths(r.byMonth, lang)
+		if lang == English {
+			months = "in " + strings.TrimPrefix(months, "of ")
+		}
+		s += " " + months
+	}
+	if len(r.byMonthDay) > 0 {
+		items := make([]string, 0, len(r.byMonthDay))
+		for _, day := range sortedDays(r.byMonthDay) {
+			items = append(items, describeMonthDay(day, lang))
+		}
+		if lang == Russian {
+			s += " " + joinList(items, lang)
+		} else {
+			s += " on " + joinList(items, lang)
+		}
+	}
+	if len(r.byDay) > 0 {
+		s += " " + r.describeByDay(lang)
+	}
+	if len(r.bySetPos) > 0 {
+		positions := make([]string, 0, len(r.bySetPos))
+		for _, pos := range sortedDays(r.bySetPos) {
+			positions = append(positions, strconv.Itoa(pos))
+		}
+		if lang == Russian {
+			s += " (позиции " + strings.Join(positions, ", ") + ")"
+		} else {
+			s += " (positions " + strings.Join(positions, ", ") + ")"
+		}
+	}
+
+	if !r.until.IsZero() {
+		if lang == Russian {
+			s += " до " + r.until.Format("02.01.2006")
+		} else {
+			s += " until " + r.until.Format("02.01.2006")
+		}
+	}
+	if r.count > 0 {
+		s += ", " + describeTimes(r.count, lang)
+	}
+	return s
+}
+
+// describeByDay описывает BYDAY: "on Monday and the last Friday",
+// "по понедельникам и в последнюю пятницу"
+func (r rrule) describeByDay(lang Language) string {
+	plain := make(map[time.Weekday]bool)
+	var ordinals []string
+	for _, day := range r.byDay {
+		if day.n == 0 {
+			plain[day.weekday] = true
+			continue
+		}
+		ordinals = append(ordinals, describeNthWeekday(day, lang))
+	}
+
+	var items []string
+	if len(plain) > 0 {
+		if lang == Russian {
+			items = append(items, "по "+joinList(weekdayNames(plain, russianWeekdaysDative), lang))
+		} else {
+			items = append(items, "on "+joinList(weekdayNames(plain, englishWeekdays), lang))
+		}
+	}
+	if len(ordinals) > 0 {
+		if lang == Russian {
+			items = append(items, joinList(ordinals, lang))
+		} else {
+			items = append(items, "on "+joinList(ordinals, lang))
+		}
+	}
+	return joinList(items, lang)
+}
+
+// describeNthWeekday описывает день недели с порядковым номером:
+// "the last Friday", "во второй вторник"
+func describeNthWeekday(day byDay, lang Language) string {
+	if lang == Russian {
+		gender := russianWeekdayGender[day.weekday]
+		ordinal, ok := russianOrdinals[day.n]
+		var word string
+		if ok {
+			word = ordinal[gender]
+		} else if day.n > 0 {
+			word = strconv.Itoa(day.n) + "-й"
+		} else {
+			word = strconv.Itoa(-day.n) + "-й с конца"
+		}
+		preposition := "в "
+		if strings.HasPrefix(word, "вт") {
+			preposition = "во "
+		}
+		return preposition + word + " " + russianWeekdaysAccusative[day.weekday]
+	}
+
+	var word string
+	switch {
+	case day.n == -1:
+		word = "last"
+	case day.n == -2:
+		word = "second-to-last"
+	case day.n > 0:
+		word = englishOrdinal(day.n)
+	default:
+		word = englishOrdinal(-day.n) + "-to-last"
+	}
+	return "the " + word + " " + englishWeekdays[day.weekday]
+}
@@ -0,0 +1,60 @@
+package repeater
+
+import (
+	"fmt"
+	"time"
+)
+
+// OverflowPolicy определяет, что делать с датой, которой нет в месяце:
+// 29 февраля в невисокосный год для правила "y" или 31 число
+// в коротком месяце для правила "m".
+type OverflowPolicy string
+
+const (
+	// OverflowDefault — поведение по умолчанию: для "y" перенос вперёд,
+	// для "m" пропуск месяца
+	OverflowDefault OverflowPolicy = ""
+	// OverflowForward — дата переносится на первое число следующего месяца
+	OverflowForward OverflowPolicy = "forward"
+	// OverflowClamp — дата переносится на последний день месяца
+	OverflowClamp OverflowPolicy = "clamp"
+	// OverflowSkip — месяц (или год) без такой даты пропускается
+	OverflowSkip OverflowPolicy = "skip"
+)
+
+// ParseOverflow разбирает политику для несуществующих дат
+func ParseOverflow(s string) (OverflowPolicy, error) {
+	switch policy := OverflowPolicy(s); policy {
+	case OverflowDefault, OverflowForward, OverflowClamp, OverflowSkip:
+		return policy, nil
+	}
+	return OverflowDefault, fmt.Errorf("invalid overflow policy: %s", s)
+}
+
+// resolve возвращает политику, которая действует для правила с кодом code
+func (p OverflowPolicy) resolve(code string) OverflowPolicy {
+	if p != OverflowDefault {
+		return p
+	}
+	if code == "y" {
+		return OverflowForward
+	}
+	return OverflowSkip
+}
+
+// dateIn возвращает дату day в месяце month года year по политике p.
+// Время суток и часовой пояс берутся из clock. false означает,
+// что по политике OverflowSkip в этом месяце даты нет.
+func (p OverflowPolicy) dateIn(year int, month time.Month, day int, clock time.Time) (time.Time, bool) {
+	if last := daysIn(year, month); day > last {
+		switch p {
+		case OverflowSkip:
+			return time.Time{}, false
+		case OverflowClamp:
+			day = last
+		default:
+			month, day = month+1, 1
+		}
+	}
+	return time.Date(year, month, day, clock.Hour(), clock.Minute(), clock.Second(), clock.Nanosecond(), clock.Location()), true
+}
@@ -2,6 +2,7 @@ package repeater
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -17,59 +18,333 @@ func stringToTime(dateString string, layout string) (time.Time, error) {
 	return parsedDate, nil
 }
 
+// NextDate возвращает следующую после now дату задачи, начатой в date
+// и повторяемой по правилу repeat. Все даты в формате 20060102.
+// Для неверного правила возвращается ошибка, оборачивающая ErrInvalidRule.
 func NextDate(now string, date string, repeat string) (string, error) {
+	return Series{Date: date, Repeat: repeat}.Next(now)
+}
+
+// Series — серия повторений задачи вместе с условиями окончания
+// и исключёнными датами. Все даты в формате 20060102.
+type Series struct {
+	// Date — дата начала серии, обычно текущая дата задачи
+	Date string
+	// Anchor — первая дата серии без переноса с нерабочего дня, от которой
+	// отсчитываются интервалы "d", годовщины "y", COUNT и UNTIL из RRULE;
+	// пустая строка означает Date
+	Anchor string
+	Repeat string
+	// Until — последняя возможная дата серии, пустая строка — без ограничения
+	Until string
+	// Count — количество дат в серии, считая Date; ноль — без ограничения
+	Count int
+	// Except — исключённые даты через запятую
+	Except string
+	// Shift — перенос дат с нерабочих дней: "", "next" или "prev"
+	Shift string
+	// Time — время суток в формате 15:04, обязательно для правил "h" и "n"
+	Time string
+	// Location — часовой пояс серии, nil означает пояс сервера
+	Location *time.Location
+	// Mode — от чего отсчитывается следующая дата при выполнении: "" или "completion"
+	Mode string
+	// Overflow — что делать с датами, которых нет в месяце: "", "forward", "clamp" или "skip"
+	Overflow string
+}
+
+// Mode определяет, от чего отсчитывается следующая дата после выполнения задачи
+type Mode string
+
+const (
+	// ScheduleMode — по расписанию: следующая дата серии после сегодняшней
+	ScheduleMode Mode = ""
+	// CompletionMode — от выполнения: серия начинается заново в день выполнения,
+	// например "d 3" означает "через 3 дня после того, как задачу сделали"
+	CompletionMode Mode = "completion"
+)
+
+// ParseMode разбирает режим повторения
+func ParseMode(s string) (Mode, error) {
+	switch mode := Mode(s); mode {
+	case ScheduleMode, CompletionMode:
+		return mode, nil
+	}
+	return ScheduleMode, fmt.Errorf("invalid recurrence mode: %s", s)
+}
+
+// Rule разбирает правило серии и применяет к нему условия окончания
+// и исключения. Дата начала не привязывается.
+func (s Series) Rule() (Rule, error) {
+	rule, err := Parse(s.Repeat)
+	if err != nil {
+		return Rule{}, err
+	}
+	if s.Until != "" {
+		untilTime, err := stringToTime(s.Until, layout)
+		if err != nil {
+			return Rule{}, fmt.Errorf("%w: invalid until date: %s", ErrInvalidRule, s.Until)
+		}
+		rule = rule.Until(untilTime)
+	}
+	if s.Count < 0 || s.Count > MaxCount {
+		return Rule{}, fmt.Errorf("%w: invalid count: %d", ErrInvalidRule, s.Count)
+	}
+	except, err := ParseDates(s.Except)
+	if err != nil {
+		return Rule{}, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+	shift, err := ParseShift(s.Shift)
+	if err != nil {
+		return Rule{}, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+	overflow, err := ParseOverflow(s.Overflow)
+	if err != nil {
+		return Rule{}, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+	return rule.Limit(s.Count).Except(except...).Shift(shift).Overflow(overflow), nil
+}
+
+// Next возвращает следующую после now дату серии.
+// Если серия закончилась, возвращается ErrNoMoreOccurrences.
+func (s Series) Next(now string) (string, error) {
 	nowTimeTime, err := stringToTime(now, layout)
 	if err != nil {
 		return "", fmt.Errorf("invalid date: %s", now)
 	}
-	startDateTimeTime, err := stringToTime(date, layout)
+	startDateTimeTime, err := stringToTime(s.Date, layout)
+	if err != nil {
+		return "", fmt.Errorf("invalid date: %s", s.Date)
+	}
+	rule, err := s.Rule()
 	if err != nil {
-		return "", fmt.Errorf("invalid date: %s", date)
+		return "", err
 	}
-	codeAndNumber := strings.Split(repeat, " ")
-	if len(codeAndNumber) == 0 || (codeAndNumber[0] != "y" && codeAndNumber[0] != "d") {
-		return "", fmt.Errorf("invalid repeat code: %s", repeat)
+	if s.Anchor != "" {
+		anchor, err := stringToTime(s.Anchor, layout)
+		if err != nil {
+			return "", fmt.Errorf("invalid date: %s", s.Anchor)
+		}
+		rule = rule.Anchor(anchor)
 	}
-	var nextTimeString string
-	if codeAndNumber[0] == "y" {
+	if rule.UsesClock() {
+		// Нужна дата позже now, поэтому моменты в течение дня now не подходят
+		nowTimeTime = nowTimeTime.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	nextTime := rule.From(startDateTimeTime).Next(nowTimeTime)
+	if nextTime.IsZero() {
+		return "", fmt.Errorf("%w for repeat rule: %s", ErrNoMoreOccurrences, s.Repeat)
+	}
+	return nextTime.Format(layout), nil
+}
 
-		for {
-			nextTime := startDateTimeTime.AddDate(1, 0, 0)
-			if nextTime.After(nowTimeTime) {
-				nextTimeString = nextTime.Format("20060102")
-				break
-			}
-			// Обновляем startDateTimeTime для следующей итерации
-			startDateTimeTime = nextTime
+// NextAt возвращает момент следующего повторения с учётом времени суток
+// и часового пояса серии. Для правил по датам это следующая после
+// сегодняшнего дня (в поясе серии) дата в то же время, а для правил "h", "n"
+// и cron — ближайший момент строго после now.
+func (s Series) NextAt(now time.Time) (time.Time, error) {
+	loc := s.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	rule, err := s.Rule()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !rule.UsesClock() {
+		nextDate, err := s.Next(now.In(loc).Format(layout))
+		if err != nil {
+			return time.Time{}, err
 		}
-		return nextTimeString, nil
+		return At(nextDate, s.Time, loc)
+	}
 
+	if s.Time == "" && rule.IsSubDaily() {
+		return time.Time{}, fmt.Errorf("%w: time is required for repeat rule: %s", ErrInvalidRule, s.Repeat)
 	}
+	start, err := At(s.Date, s.Time, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	nextTime := rule.From(start).Next(now)
+	if nextTime.IsZero() {
+		return time.Time{}, fmt.Errorf("%w for repeat rule: %s", ErrNoMoreOccurrences, s.Repeat)
+	}
+	return nextTime.In(loc), nil
+}
 
-	if codeAndNumber[0] == "d" {
-		if len(codeAndNumber) != 2 {
-			return "", fmt.Errorf("invalid day repeat format: %s", repeat)
+// Done возвращает момент следующего повторения после выполнения задачи в момент at.
+// В режиме по расписанию это то же, что NextAt. В режиме от выполнения серия
+// отсчитывается от дня выполнения, а для правил "h" и "n" — от его момента.
+func (s Series) Done(at time.Time) (time.Time, error) {
+	mode, err := ParseMode(s.Mode)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+	if mode == ScheduleMode {
+		return s.NextAt(at)
+	}
+
+	loc := s.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	at = at.In(loc)
+	rule, err := s.Rule()
+	if err != nil {
+		return time.Time{}, err
+	}
+	restarted := s
+	restarted.Mode = string(ScheduleMode)
+	restarted.Date = at.Format(layout)
+	restarted.Anchor = ""
+	if !rule.IsSubDaily() {
+		return restarted.NextAt(at)
+	}
+
+	// Время выполнения становится временем задачи, чтобы интервал
+	// отсчитывался от него
+	if s.Time == "" {
+		return time.Time{}, fmt.Errorf("%w: time is required for repeat rule: %s", ErrInvalidRule, s.Repeat)
+	}
+	start := at.Truncate(time.Minute)
+	nextTime := rule.From(start).Next(start)
+	if nextTime.IsZero() {
+		return time.Time{}, fmt.Errorf("%w for repeat rule: %s", ErrNoMoreOccurrences, s.Repeat)
+	}
+	return nextTime.In(loc), nil
+}
+
+// ParseDates разбирает список дат через запятую. Пустая строка — пустой список.
+func ParseDates(s string) ([]time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var dates []time.Time
+	for _, part := range strings.Split(s, ",") {
+		date, err := stringToTime(part, layout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date: %s", part)
+		}
+		dates = append(dates, date)
+	}
+	return dates, nil
+}
+
+// JoinDates сортирует даты, убирает повторы и соединяет их через запятую
+func JoinDates(dates []time.Time) string {
+	formatted := make([]string, 0, len(dates))
+	for _, date := range dates {
+		formatted = append(formatted, date.Format(layout))
+	}
+	sort.Strings(formatted)
+	var parts []string
+	for i, date := range formatted {
+		if i > 0 && date == formatted[i-1] {
+			continue
 		}
+		parts = append(parts, date)
+	}
+	return strings.Join(parts, ",")
+}
 
-		i, err := strconv.Atoi(codeAndNumber[1])
+// parseMonthDays разбирает список дней месяца вида "1,15,-1".
+// Допустимы числа от 1 до 31, а также -1 (последний день) и -2 (предпоследний).
+func parseMonthDays(s string) ([]int, error) {
+	var days []int
+	for _, part := range strings.Split(s, ",") {
+		day, err := strconv.Atoi(part)
 		if err != nil {
-			return "", fmt.Errorf("error converting string to int: %s", repeat)
+			return nil, fmt.Errorf("invalid month day: %s", part)
 		}
-		if i > 400 {
-			return "", nil
+		if day == 0 || day > 31 || day < -2 {
+			return nil, fmt.Errorf("invalid month day: %s", part)
 		}
+		days = append(days, day)
+	}
+	return days, nil
+}
 
-		for {
-			nextTime := startDateTimeTime.AddDate(0, 0, i)
-			if nextTime.After(nowTimeTime) {
-				nextTimeString = nextTime.Format("20060102")
-				break
+// parseMonths разбирает список номеров месяцев вида "1,6,12".
+func parseMonths(s string) (map[time.Month]bool, error) {
+	months := make(map[time.Month]bool)
+	for _, part := range strings.Split(s, ",") {
+		month, err := strconv.Atoi(part)
+		if err != nil || month < 1 || month > 12 {
+			return nil, fmt.Errorf("invalid month: %s", part)
+		}
+		months[time.Month(month)] = true
+	}
+	return months, nil
+}
+
+// daysIn возвращает количество дней в месяце
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// monthDaysExist сообщает, бывает ли хотя бы одно из чисел days хотя бы
+// в одном из месяцев months (пустой months означает любой месяц).
+// Февраль считается високосным, отрицательные числа отсчитываются от конца.
+func monthDaysExist(days []int, months map[time.Month]bool) bool {
+	if len(days) == 0 || len(months) == 0 {
+		return true
+	}
+	for month := range months {
+		// 2000 — високосный год, в нём у февраля 29 дней
+		last := daysIn(2000, month)
+		for _, day := range days {
+			if day <= last && -day <= last {
+				return true
 			}
-			// Обновляем startDateTimeTime для следующей итерации
-			startDateTimeTime = nextTime
 		}
-		return nextTimeString, nil
 	}
+	return false
+}
+
+// nextMonthDay ищет первую дату строго после from, число которой входит в days,
+// а месяц — в months (пустой months означает любой месяц). Числа, которых
+// нет в месяце, обрабатываются по политике policy.
+func nextMonthDay(from time.Time, days []int, months map[time.Month]bool, policy OverflowPolicy) (time.Time, bool) {
+	year, month, _ := from.Date()
+	midnight := time.Date(year, month, 1, 0, 0, 0, 0, from.Location())
+	// За 10 лет найдётся любая допустимая комбинация, включая 29 февраля
+	for i := 0; i < 12*10; i++ {
+		if len(months) == 0 || months[month] {
+			last := daysIn(year, month)
+			var best time.Time
+			for _, day := range days {
+				if day < 0 {
+					day = last + day + 1
+				}
+				candidate, ok := policy.dateIn(year, month, day, midnight)
+				if ok && candidate.After(from) && (best.IsZero() || candidate.Before(best)) {
+					best = candidate
+				}
+			}
+			if !best.IsZero() {
+				return best, true
+			}
+		}
+		month++
+		if month > time.December {
+			month = time.January
+			year++
+		}
+	}
+	return time.Time{}, false
+}
 
-	return "", fmt.Errorf("unknown repeat code: %s", codeAndNumber[0])
+// parseWeekdays разбирает список дней недели вида "1,3,5",
+// где 1 — понедельник, а 7 — воскресенье.
+func parseWeekdays(s string) (map[time.Weekday]bool, error) {
+	weekdays := make(map[time.Weekday]bool)
+	for _, part := range strings.Split(s, ",") {
+		day, err := strconv.Atoi(part)
+		if err != nil || day < 1 || day > 7 {
+			return nil, fmt.Errorf("invalid weekday: %s", part)
+		}
+		weekdays[time.Weekday(day%7)] = true
+	}
+	return weekdays, nil
 }
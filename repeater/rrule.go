@@ -0,0 +1,427 @@
+package repeater
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Правила повторения в формате iCalendar (RFC 5545), например
+// "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;UNTIL=20241231".
+// Поддерживаются частоты DAILY, WEEKLY, MONTHLY и YEARLY, а также
+// части INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY, BYMONTH, BYSETPOS и WKST.
+
+// Григорианский календарь повторяется каждые 400 лет вместе с днями недели,
+// поэтому если за 400 лет подряд не нашлось ни одной даты, её нет вовсе
+const maxRRuleYears = 400
+
+var rruleWeekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// byDay — элемент BYDAY: день недели с необязательным порядковым номером,
+// например "-1FR" — последняя пятница месяца (или года).
+type byDay struct {
+	weekday time.Weekday
+	n       int
+}
+
+type rrule struct {
+	freq       string
+	interval   int
+	count      int
+	until      time.Time
+	byDay      []byDay
+	byMonthDay []int
+	byMonth    map[time.Month]bool
+	bySetPos   []int
+	wkst       time.Weekday
+}
+
+// isRRule сообщает, записано ли правило повторения в формате RRULE
+func isRRule(repeat string) bool {
+	return strings.Contains(strings.ToUpper(repeat), "FREQ=")
+}
+
+// parseRRule разбирает строку RRULE. Префикс "RRULE:" необязателен.
+func parseRRule(repeat string) (rrule, error) {
+	r := rrule{interval: 1, wkst: time.Monday}
+	s := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(repeat)), "RRULE:")
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return rrule{}, fmt.Errorf("invalid rrule part: %s", part)
+		}
+		if seen[name] {
+			return rrule{}, fmt.Errorf("duplicate rrule part: %s", name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			switch value {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				r.freq = value
+			default:
+				return rrule{}, fmt.Errorf("unsupported rrule frequency: %s", value)
+			}
+		case "INTERVAL":
+			r.interval, err = strconv.Atoi(value)
+			if err != nil || r.interval < 1 {
+				return rrule{}, fmt.Errorf("invalid rrule interval: %s", value)
+			}
+		case "COUNT":
+			r.count, err = strconv.Atoi(value)
+			if err != nil || r.count < 1 || r.count > MaxCount {
+				return rrule{}, fmt.Errorf("invalid rrule count: %s", value)
+			}
+		case "UNTIL":
+			// Время суток не учитывается: задачи хранят только дату
+			if len(value) < 8 {
+				return rrule{}, fmt.Errorf("invalid rrule until: %s", value)
+			}
+			r.until, err = time.Parse(layout, value[:8])
+			if err != nil {
+				return rrule{}, fmt.Errorf("invalid rrule until: %s", value)
+			}
+		case "BYDAY":
+			for _, item := range strings.Split(value, ",") {
+				day, err := parseByDay(item)
+				if err != nil {
+					return rrule{}, err
+				}
+				r.byDay = append(r.byDay, day)
+			}
+		case "BYMONTHDAY":
+			for _, item := range strings.Split(value, ",") {
+				day, err := strconv.Atoi(item)
+				if err != nil || day == 0 || day > 31 || day < -31 {
+					return rrule{}, fmt.Errorf("invalid rrule month day: %s", item)
+				}
+				r.byMonthDay = append(r.byMonthDay, day)
+			}
+		case "BYMONTH":
+			r.byMonth, err = parseMonths(value)
+			if err != nil {
+				return rrule{}, err
+			}
+		case "BYSETPOS":
+			for _, item := range strings.Split(value, ",") {
+				pos, err := strconv.Atoi(item)
+				if err != nil || pos == 0 || pos > 366 || pos < -366 {
+					return rrule{}, fmt.Errorf("invalid rrule set position: %s", item)
+				}
+				r.bySetPos = append(r.bySetPos, pos)
+			}
+		case "WKST":
+			weekday, ok := rruleWeekdays[value]
+			if !ok {
+				return rrule{}, fmt.Errorf("invalid rrule week start: %s", value)
+			}
+			r.wkst = weekday
+		default:
+			return rrule{}, fmt.Errorf("unsupported rrule part: %s", name)
+		}
+	}
+
+	if r.freq == "" {
+		return rrule{}, fmt.Errorf("rrule frequency is required: %s", repeat)
+	}
+	if r.count != 0 && !r.until.IsZero() {
+		return rrule{}, fmt.Errorf("rrule must not contain both COUNT and UNTIL: %s", repeat)
+	}
+	if len(r.bySetPos) > 0 && len(r.byDay) == 0 && len(r.byMonthDay) == 0 && len(r.byMonth) == 0 {
+		return rrule{}, fmt.Errorf("rrule BYSETPOS requires another BY part: %s", repeat)
+	}
+	for _, day := range r.byDay {
+		if day.n != 0 && r.freq != "MONTHLY" && r.freq != "YEARLY" {
+			return rrule{}, fmt.Errorf("rrule BYDAY ordinals require MONTHLY or YEARLY frequency: %s", repeat)
+		}
+	}
+	if !monthDaysExist(r.byMonthDay, r.byMonth) {
+		return rrule{}, fmt.Errorf("rrule BYMONTHDAY never occurs in BYMONTH: %s", repeat)
+	}
+	return r, nil
+}
+
+// parseByDay разбирает элемент BYDAY вида "MO", "2TU" или "-1FR"
+func parseByDay(s string) (byDay, error) {
+	if len(s) < 2 {
+		return byDay{}, fmt.Errorf("invalid rrule weekday: %s", s)
+	}
+	weekday, ok := rruleWeekdays[s[len(s)-2:]]
+	if !ok {
+		return byDay{}, fmt.Errorf("invalid rrule weekday: %s", s)
+	}
+	day := byDay{weekday: weekday}
+	if prefix := s[:len(s)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 || n > 53 || n < -53 {
+			return byDay{}, fmt.Errorf("invalid rrule weekday: %s", s)
+		}
+		day.n = n
+	}
+	return day, nil
+}
+
+// maxEmptyPeriods возвращает, сколько периодов подряд без дат укладывается
+// в maxRRuleYears лет
+func (r rrule) maxEmptyPeriods() int {
+	var periods int
+	switch r.freq {
+	case "DAILY":
+		periods = 146097 // дней в 400 годах
+	case "WEEKLY":
+		periods = 20871 // полных недель в 400 годах
+	case "MONTHLY":
+		periods = 12 * maxRRuleYears
+	default:
+		periods = maxRRuleYears
+	}
+	return periods/r.interval + 1
+}
+
+// periodStart возвращает начало периода с номером k, считая от периода,
+// в который попадает дата начала серии.
+func (r rrule) periodStart(start time.Time, k int) time.Time {
+	switch r.freq {
+	case "DAILY":
+		return start.AddDate(0, 0, k*r.interval)
+	case "WEEKLY":
+		offset := (int(start.Weekday()) - int(r.wkst) + 7) % 7
+		return start.AddDate(0, 0, -offset+7*k*r.interval)
+	case "MONTHLY":
+		return time.Date(start.Year(), start.Month()+time.Month(k*r.interval), 1, 0, 0, 0, 0, start.Location())
+	default:
+		return time.Date(start.Year()+k*r.interval, time.January, 1, 0, 0, 0, 0, start.Location())
+	}
+}
+
+// periodEnd возвращает дату, следующую за последним днём периода
+func (r rrule) periodEnd(periodStart time.Time) time.Time {
+	switch r.freq {
+	case "DAILY":
+		return periodStart.AddDate(0, 0, 1)
+	case "WEEKLY":
+		return periodStart.AddDate(0, 0, 7)
+	case "MONTHLY":
+		return periodStart.AddDate(0, 1, 0)
+	default:
+		return periodStart.AddDate(1, 0, 0)
+	}
+}
+
+// firstPeriod оценивает номер периода, с которого имеет смысл искать даты
+// после from. При COUNT пропускать периоды нельзя: их нужно пересчитать.
+func (r rrule) firstPeriod(start, from time.Time) int {
+	if r.count != 0 || !from.After(start) {
+		return 0
+	}
+	var k int
+	switch r.freq {
+	case "DAILY":
+		k = int(from.Sub(start).Hours()/24) / r.interval
+	case "WEEKLY":
+		k = int(from.Sub(start).Hours()/24/7) / r.interval
+	case "MONTHLY":
+		months := (from.Year()-start.Year())*12 + int(from.Month()) - int(start.Month())
+		k = months / r.interval
+	default:
+		k = (from.Year() - start.Year()) / r.interval
+	}
+	if k > 0 {
+		k--
+	}
+	return k
+}
+
+// matches проверяет, подходит ли день под части BYMONTH, BYMONTHDAY и BYDAY
+func (r rrule) matches(day, start time.Time) bool {
+	if len(r.byMonth) > 0 && !r.byMonth[day.Month()] {
+		return false
+	}
+
+	monthDays := r.byMonthDay
+	weekdays := r.byDay
+	// Если правило не уточняет дни, они берутся из даты начала серии
+	if len(monthDays) == 0 && len(weekdays) == 0 {
+		switch r.freq {
+		case "WEEKLY":
+			weekdays = []byDay{{weekday: start.Weekday()}}
+		case "MONTHLY":
+			monthDays = []int{start.Day()}
+		case "YEARLY":
+			if len(r.byMonth) == 0 && day.Month() != start.Month() {
+				return false
+			}
+			monthDays = []int{start.Day()}
+		}
+	}
+
+	if len(monthDays) > 0 {
+		last := daysIn(day.Year(), day.Month())
+		found := false
+		for _, md := range monthDays {
+			if md < 0 {
+				md = last + md + 1
+			}
+			if md == day.Day() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(weekdays) > 0 {
+		found := false
+		for _, wd := range weekdays {
+			if wd.weekday != day.Weekday() {
+				continue
+			}
+			if wd.n == 0 || wd.n == r.weekdayOrdinal(day, wd.n < 0) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// weekdayOrdinal возвращает порядковый номер дня недели в месяце,
+// а для YEARLY без BYMONTH — в году. При fromEnd номер отрицательный
+// и отсчитывается от конца.
+func (r rrule) weekdayOrdinal(day time.Time, fromEnd bool) int {
+	index, total := day.Day(), daysIn(day.Year(), day.Month())
+	if r.freq == "YEARLY" && len(r.byMonth) == 0 {
+		index = day.YearDay()
+		total = time.Date(day.Year(), time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+	}
+	if fromEnd {
+		return -((total-index)/7 + 1)
+	}
+	return (index-1)/7 + 1
+}
+
+// occurrences возвращает отсортированные даты серии внутри периода
+func (r rrule) occurrences(periodStart, start time.Time) []time.Time {
+	var dates []time.Time
+	end := r.periodEnd(periodStart)
+	for day := periodStart; day.Before(end); day = day.AddDate(0, 0, 1) {
+		if r.matches(day, start) {
+			dates = append(dates, day)
+		}
+	}
+	if len(r.bySetPos) == 0 {
+		return dates
+	}
+
+	var selected []time.Time
+	for _, pos := range r.bySetPos {
+		i := pos - 1
+		if pos < 0 {
+			i = len(dates) + pos
+		}
+		if i >= 0 && i < len(dates) {
+			selected = append(selected, dates[i])
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].Before(selected[j]) })
+	return selected
+}
+
+// next возвращает первую дату серии, начатой в start, которая строго позже from.
+// Второе значение равно false, если серия закончилась (COUNT или UNTIL).
+func (r rrule) next(start, from time.Time) (time.Time, bool) {
+	count, empty := 0, 0
+	for k := r.firstPeriod(start, from); empty < r.maxEmptyPeriods(); k++ {
+		dates := r.occurrences(r.periodStart(start, k), start)
+		if len(dates) == 0 {
+			empty++
+			continue
+		}
+		empty = 0
+		for _, date := range dates {
+			if date.Before(start) {
+				continue
+			}
+			if !r.until.IsZero() && date.After(r.until) {
+				return time.Time{}, false
+			}
+			count++
+			if r.count != 0 && count > r.count {
+				return time.Time{}, false
+			}
+			if date.After(from) {
+				return date, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// String возвращает правило в каноническом виде
+func (r rrule) String() string {
+	parts := []string{"FREQ=" + r.freq}
+	if r.interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.interval))
+	}
+	if r.count != 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.count))
+	}
+	if !r.until.IsZero() {
+		parts = append(parts, "UNTIL="+r.until.Format(layout))
+	}
+	if len(r.byMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinMonths(r.byMonth))
+	}
+	if len(r.byMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.byMonthDay))
+	}
+	if len(r.byDay) > 0 {
+		days := make([]string, 0, len(r.byDay))
+		for _, day := range r.byDay {
+			name := weekdayCode(day.weekday)
+			if day.n != 0 {
+				name = strconv.Itoa(day.n) + name
+			}
+			days = append(days, name)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.bySetPos) > 0 {
+		parts = append(parts, "BYSETPOS="+joinInts(r.bySetPos))
+	}
+	if r.wkst != time.Monday {
+		parts = append(parts, "WKST="+weekdayCode(r.wkst))
+	}
+	return strings.Join(parts, ";")
+}
+
+// weekdayCode возвращает двухбуквенный код дня недели из RFC 5545
+func weekdayCode(weekday time.Weekday) string {
+	for code, wd := range rruleWeekdays {
+		if wd == weekday {
+			return code
+		}
+	}
+	return ""
+}
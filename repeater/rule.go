@@ -0,0 +1,510 @@
+package repeater
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Максимальный интервал для правила "d". Следующая дата вычисляется
+// арифметически при любом интервале, а ограничение входит в формат правила:
+// "d 401" считается ошибкой, как и неизвестный код.
+const maxDayInterval = 400
+
+// Максимальные интервалы для правил "h" и "n": неделя и сутки
+const (
+	maxHourInterval   = 7 * 24
+	maxMinuteInterval = 24 * 60
+)
+
+var (
+	// ErrNoMoreOccurrences возвращается, когда серия повторений закончилась
+	ErrNoMoreOccurrences = errors.New("no more occurrences")
+	// ErrInvalidRule возвращается для любого неверного правила повторения
+	// или неверных условий серии: неизвестного кода, интервала вне допустимых
+	// пределов, неверной строки RRULE или cron и т. п.
+	ErrInvalidRule = errors.New("invalid repeat rule")
+)
+
+// Rule — разобранное правило повторения задачи.
+// Поддерживаются короткие коды "d N", "b N" (каждые N рабочих дней), "y",
+// "m ДНИ [МЕСЯЦЫ]", "w ДНИ_НЕДЕЛИ", строки RRULE из RFC 5545, а также
+// правила чаще раза в день: "h N" (каждые N часов) и "n N" (каждые N минут)
+// с необязательным окном "ЧЧ:ММ-ЧЧ:ММ", например "n 30 09:00-17:00",
+// и выражения cron из пяти полей или макросы вида @daily.
+type Rule struct {
+	code     string
+	interval int
+	days     []int
+	months   map[time.Month]bool
+	weekdays map[time.Weekday]bool
+	rrule    rrule
+	cron     cronSpec
+	start    time.Time
+	anchor   time.Time
+	until    time.Time
+	count    int
+	except   map[string]bool
+	shift    ShiftMode
+	overflow OverflowPolicy
+	// Окно для правил "h" и "n" в минутах от начала суток
+	window               bool
+	windowFrom, windowTo int
+}
+
+// Parse разбирает строку правила повторения.
+// Ошибка разбора всегда оборачивает ErrInvalidRule.
+func Parse(repeat string) (Rule, error) {
+	rule, err := parse(repeat)
+	if err != nil {
+		return Rule{}, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+	return rule, nil
+}
+
+func parse(repeat string) (Rule, error) {
+	if isRRule(repeat) {
+		r, err := parseRRule(repeat)
+		if err != nil {
+			return Rule{}, err
+		}
+		return Rule{code: "rrule", rrule: r}, nil
+	}
+	if isCron(repeat) {
+		c, err := parseCron(repeat)
+		if err != nil {
+			return Rule{}, err
+		}
+		return Rule{code: "cron", cron: c}, nil
+	}
+
+	codeAndNumber := strings.Split(repeat, " ")
+	rule := Rule{code: codeAndNumber[0]}
+	switch rule.code {
+	case "y":
+		if len(codeAndNumber) != 1 {
+			return Rule{}, fmt.Errorf("invalid year repeat format: %s", repeat)
+		}
+	case "d", "b":
+		if len(codeAndNumber) != 2 {
+			return Rule{}, fmt.Errorf("invalid day repeat format: %s", repeat)
+		}
+		i, err := strconv.Atoi(codeAndNumber[1])
+		if err != nil {
+			return Rule{}, fmt.Errorf("error converting string to int: %s", repeat)
+		}
+		if i < 1 || i > maxDayInterval {
+			return Rule{}, fmt.Errorf("day interval must be between 1 and %d: %s", maxDayInterval, repeat)
+		}
+		rule.interval = i
+	case "h", "n":
+		if len(codeAndNumber) != 2 && len(codeAndNumber) != 3 {
+			return Rule{}, fmt.Errorf("invalid clock repeat format: %s", repeat)
+		}
+		i, err := strconv.Atoi(codeAndNumber[1])
+		if err != nil {
+			return Rule{}, fmt.Errorf("error converting string to int: %s", repeat)
+		}
+		maxInterval := maxHourInterval
+		if rule.code == "n" {
+			maxInterval = maxMinuteInterval
+		}
+		if i < 1 || i > maxInterval {
+			return Rule{}, fmt.Errorf("interval must be between 1 and %d: %s", maxInterval, repeat)
+		}
+		rule.interval = i
+		if len(codeAndNumber) == 3 {
+			rule.window = true
+			rule.windowFrom, rule.windowTo, err = parseWindow(codeAndNumber[2])
+			if err != nil {
+				return Rule{}, err
+			}
+		}
+	case "m":
+		if len(codeAndNumber) != 2 && len(codeAndNumber) != 3 {
+			return Rule{}, fmt.Errorf("invalid month repeat format: %s", repeat)
+		}
+		days, err := parseMonthDays(codeAndNumber[1])
+		if err != nil {
+			return Rule{}, err
+		}
+		rule.days = days
+		if len(codeAndNumber) == 3 {
+			rule.months, err = parseMonths(codeAndNumber[2])
+			if err != nil {
+				return Rule{}, err
+			}
+		}
+		if !monthDaysExist(rule.days, rule.months) {
+			return Rule{}, fmt.Errorf("month days never occur in the given months: %s", repeat)
+		}
+	case "w":
+		if len(codeAndNumber) != 2 {
+			return Rule{}, fmt.Errorf("invalid week repeat format: %s", repeat)
+		}
+		weekdays, err := parseWeekdays(codeAndNumber[1])
+		if err != nil {
+			return Rule{}, err
+		}
+		rule.weekdays = weekdays
+	default:
+		return Rule{}, fmt.Errorf("invalid repeat code: %s", repeat)
+	}
+	return rule, nil
+}
+
+// parseWindow разбирает окно вида "09:00-17:00" и возвращает его границы
+// в минутах от начала суток
+func parseWindow(s string) (int, int, error) {
+	fromStr, toStr, ok := strings.Cut(s, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid time window: %s", s)
+	}
+	from, err := time.Parse(clockLayout, fromStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid time window: %s", s)
+	}
+	to, err := time.Parse(clockLayout, toStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid time window: %s", s)
+	}
+	fromMinutes := from.Hour()*60 + from.Minute()
+	toMinutes := to.Hour()*60 + to.Minute()
+	if fromMinutes > toMinutes {
+		return 0, 0, fmt.Errorf("time window must not cross midnight: %s", s)
+	}
+	return fromMinutes, toMinutes, nil
+}
+
+// IsSubDaily сообщает, повторяется ли правило чаще раза в день
+// и поэтому требует у задачи время суток
+func (r Rule) IsSubDaily() bool {
+	return r.code == "h" || r.code == "n"
+}
+
+// UsesClock сообщает, работает ли правило с моментами времени, а не с датами.
+// Кроме "h" и "n" это cron, в котором время задаёт само выражение.
+func (r Rule) UsesClock() bool {
+	return r.IsSubDaily() || r.code == "cron"
+}
+
+// From возвращает копию правила, привязанную к дате начала серии.
+// Для "d", "y" и RRULE от неё отсчитываются интервалы, COUNT и UNTIL.
+func (r Rule) From(start time.Time) Rule {
+	r.start = start
+	return r
+}
+
+// Anchor возвращает копию правила, в котором интервалы "d", годовщины "y",
+// а также COUNT и UNTIL из RRULE отсчитываются от даты anchor — первой даты
+// серии, — а не от даты начала, заданной через From. Так серия не начинается
+// заново, когда дата задачи переносится на следующее повторение или
+// с нерабочего дня.
+func (r Rule) Anchor(anchor time.Time) Rule {
+	r.anchor = anchor
+	return r
+}
+
+// Until возвращает копию правила, серия которого заканчивается датой until
+func (r Rule) Until(until time.Time) Rule {
+	r.until = until
+	return r
+}
+
+// MaxCount — наибольшее количество дат в серии с ограничением Limit или COUNT.
+// Даты такой серии перебираются с начала, поэтому количество ограничено.
+const MaxCount = 10000
+
+// Limit возвращает копию правила, серия которого состоит не более чем
+// из count дат, включая дату начала. Ноль снимает ограничение.
+func (r Rule) Limit(count int) Rule {
+	r.count = count
+	return r
+}
+
+// Except возвращает копию правила, в серии которого пропускаются даты dates.
+// Пропущенные даты всё равно учитываются в ограничении Limit.
+func (r Rule) Except(dates ...time.Time) Rule {
+	except := make(map[string]bool, len(r.except)+len(dates))
+	for date := range r.except {
+		except[date] = true
+	}
+	for _, date := range dates {
+		except[date.Format(layout)] = true
+	}
+	r.except = except
+	return r
+}
+
+// Shift возвращает копию правила, даты которого, выпавшие на нерабочий
+// день, переносятся согласно mode
+func (r Rule) Shift(mode ShiftMode) Rule {
+	r.shift = mode
+	return r
+}
+
+// Overflow возвращает копию правила с политикой для дат, которых нет в месяце
+func (r Rule) Overflow(policy OverflowPolicy) Rule {
+	r.overflow = policy
+	return r
+}
+
+// Next возвращает первую дату повторения строго после after.
+// Если правило не привязано к дате начала через From, серия считается
+// начатой в after. Нулевое время означает, что повторений больше нет.
+func (r Rule) Next(after time.Time) time.Time {
+	if r.start.IsZero() {
+		r.start = after
+	}
+	floor := after
+	if r.start.After(floor) {
+		floor = r.start
+	}
+	cursor := after
+	if r.shift == ShiftNext {
+		// Дата незадолго до after могла быть перенесена на день после него
+		cursor = after.AddDate(0, 0, -maxShiftDays)
+	}
+	for {
+		var scheduled time.Time
+		if r.count > 0 {
+			scheduled = r.nextLimited(cursor)
+		} else {
+			scheduled = r.next(cursor)
+		}
+		// Дата не позже курсора означает ошибку в правиле, и перебор
+		// на ней бы зациклился
+		if scheduled.IsZero() || !scheduled.After(cursor) {
+			return time.Time{}
+		}
+		cursor = scheduled
+
+		nextTime := shiftDate(scheduled, r.shift)
+		// Until сравнивается по датам, чтобы не отсечь время в последний день
+		if nextTime.IsZero() || (!r.until.IsZero() && nextTime.Format(layout) > r.until.Format(layout)) {
+			return time.Time{}
+		}
+		if nextTime.After(floor) && !r.except[nextTime.Format(layout)] {
+			return nextTime
+		}
+	}
+}
+
+// nextLimited перебирает даты серии с начала, чтобы учесть ограничение Limit
+func (r Rule) nextLimited(after time.Time) time.Time {
+	if r.start.IsZero() {
+		r.start = after
+	}
+	date := r.start
+	for i := 1; i < r.count; i++ {
+		date = r.next(date)
+		if date.IsZero() || date.After(after) {
+			return date
+		}
+	}
+	return time.Time{}
+}
+
+// next возвращает следующую дату без учёта Until и Limit
+func (r Rule) next(after time.Time) time.Time {
+	start := r.start
+	if start.IsZero() {
+		start = after
+	}
+	// Следующая дата должна быть позже и after, и даты начала
+	from := start
+	if after.After(from) {
+		from = after
+	}
+
+	switch r.code {
+	case "y":
+		// Годовщины считаются от первой даты серии, а не друг от друга
+		// и не от текущей даты задачи, чтобы 29 февраля после переноса
+		// на 28 февраля или 1 марта вернулось в високосный год
+		policy := r.overflow.resolve(r.code)
+		anchor := r.anchorFor(start)
+		year := from.Year()
+		// 29 февраля встречается хотя бы раз за 8 лет
+		for limit := year + 8; year <= limit; year++ {
+			nextTime, ok := policy.dateIn(year, anchor.Month(), anchor.Day(), anchor)
+			if ok && nextTime.After(from) {
+				return nextTime
+			}
+		}
+	case "d":
+		// Число целых интервалов между start и from считается по календарным
+		// дням, поэтому переходы на летнее время не сдвигают даты
+		// Интервалы отсчитываются от первой даты серии без переноса
+		// с нерабочего дня, иначе после переноса серия сбилась бы на день
+		anchor := r.anchorFor(start)
+		k := (civilDay(from) - civilDay(anchor)) / r.interval
+		nextTime := anchor.AddDate(0, 0, k*r.interval)
+		if !nextTime.After(from) {
+			nextTime = nextTime.AddDate(0, 0, r.interval)
+		}
+		return nextTime
+	case "b":
+		for {
+			nextTime := addWorkdays(start, r.interval)
+			if nextTime.IsZero() || nextTime.After(from) {
+				return nextTime
+			}
+			start = nextTime
+		}
+	case "h", "n":
+		return r.nextClock(start, from)
+	case "m":
+		nextTime, _ := nextMonthDay(from, r.days, r.months, r.overflow.resolve(r.code))
+		return nextTime
+	case "w":
+		// Любой день недели встретится в ближайшие 7 дней
+		for i := 1; i <= 7; i++ {
+			nextTime := from.AddDate(0, 0, i)
+			if r.weekdays[nextTime.Weekday()] {
+				return nextTime
+			}
+		}
+	case "rrule":
+		nextTime, _ := r.rrule.next(r.anchorFor(start), from)
+		return nextTime
+	case "cron":
+		return r.cron.next(from)
+	}
+	return time.Time{}
+}
+
+// anchorFor возвращает первую дату серии, начатой в start
+func (r Rule) anchorFor(start time.Time) time.Time {
+	if !r.anchor.IsZero() && r.anchor.Before(start) {
+		return r.anchor
+	}
+	return start
+}
+
+// civilDay возвращает номер календарного дня t по местным часам
+func civilDay(t time.Time) int {
+	year, month, day := t.Date()
+	return int(time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60))
+}
+
+// nextClock возвращает следующий момент для правил "h" и "n" строго после from.
+// Без окна моменты отсчитываются от start через равные промежутки времени,
+// а с окном — каждый день от начала окна по местным часам.
+func (r Rule) nextClock(start, from time.Time) time.Time {
+	step := time.Duration(r.interval) * time.Minute
+	if r.code == "h" {
+		step = time.Duration(r.interval) * time.Hour
+	}
+	if !r.window {
+		// Шаги считаются в секундах: разность в time.Duration
+		// переполняется, если серия началась больше 292 лет назад
+		stepSeconds := int64(step / time.Second)
+		k := (from.Unix()-start.Unix())/stepSeconds + 1
+		return time.Unix(start.Unix()+k*stepSeconds, int64(start.Nanosecond())).In(start.Location())
+	}
+
+	stepMinutes := int(step / time.Minute)
+	from = from.In(start.Location())
+	year, month, day := from.Date()
+	// Если окно на сегодня уже закончилось, подойдёт начало завтрашнего
+	for i := 0; i < 2; i++ {
+		for minute := r.windowFrom; minute <= r.windowTo; minute += stepMinutes {
+			nextTime := time.Date(year, month, day+i, 0, minute, 0, 0, start.Location())
+			if nextTime.After(from) {
+				return nextTime
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// String возвращает правило в каноническом виде: списки отсортированы,
+// повторы и ведущие нули убраны.
+func (r Rule) String() string {
+	switch r.code {
+	case "d", "b":
+		return r.code + " " + strconv.Itoa(r.interval)
+	case "h", "n":
+		s := r.code + " " + strconv.Itoa(r.interval)
+		if r.window {
+			s += fmt.Sprintf(" %02d:%02d-%02d:%02d", r.windowFrom/60, r.windowFrom%60, r.windowTo/60, r.windowTo%60)
+		}
+		return s
+	case "m":
+		s := "m " + joinInts(r.days)
+		if len(r.months) > 0 {
+			s += " " + joinMonths(r.months)
+		}
+		return s
+	case "w":
+		weekdays := make([]int, 0, len(r.weekdays))
+		for weekday := range r.weekdays {
+			// Воскресенье в правилах обозначается числом 7
+			day := int(weekday)
+			if day == 0 {
+				day = 7
+			}
+			weekdays = append(weekdays, day)
+		}
+		return "w " + joinInts(weekdays)
+	case "rrule":
+		return r.rrule.String()
+	case "cron":
+		return r.cron.expr
+	}
+	return r.code
+}
+
+// joinInts сортирует числа и соединяет их через запятую.
+// Положительные числа идут по возрастанию, отрицательные — после них.
+func joinInts(nums []int) string {
+	sorted := append([]int(nil), nums...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if (sorted[i] < 0) != (sorted[j] < 0) {
+			return sorted[i] > 0
+		}
+		if sorted[i] < 0 {
+			return sorted[i] > sorted[j]
+		}
+		return sorted[i] < sorted[j]
+	})
+	parts := make([]string, 0, len(sorted))
+	for i, n := range sorted {
+		if i > 0 && n == sorted[i-1] {
+			continue
+		}
+		parts = append(parts, strconv.Itoa(n))
+	}
+	return strings.Join(parts, ",")
+}
+
+// joinMonths соединяет номера месяцев через запятую
+func joinMonths(months map[time.Month]bool) string {
+	nums := make([]int, 0, len(months))
+	for month := range months {
+		nums = append(nums, int(month))
+	}
+	return joinInts(nums)
+}
+
+// Occurrences возвращает не больше count дат повторения строго после after.
+// Если until не нулевое, даты позже until не возвращаются.
+func (r Rule) Occurrences(after, until time.Time, count int) []time.Time {
+	if r.start.IsZero() {
+		r.start = after
+	}
+	var dates []time.Time
+	for len(dates) < count {
+		nextTime := r.Next(after)
+		if nextTime.IsZero() || (!until.IsZero() && nextTime.After(until)) {
+			break
+		}
+		dates = append(dates, nextTime)
+		after = nextTime
+	}
+	return dates
+}
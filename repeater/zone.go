@@ -0,0 +1,35 @@
+package repeater
+
+import (
+	"fmt"
+	"time"
+)
+
+// Формат времени суток у задач
+const clockLayout = "15:04"
+
+// Today возвращает текущую дату в часовом поясе loc в формате 20060102.
+// Именно её нужно передавать в NextDate как now для задач в этом поясе.
+func Today(loc *time.Location) string {
+	return time.Now().In(loc).Format(layout)
+}
+
+// At возвращает момент, когда в часовом поясе loc наступает дата date
+// (формат 20060102) и время clock (формат 15:04). Пустое clock означает
+// начало суток. Время, которое при переходе на летнее время пропускается
+// или повторяется, разрешается так же, как в time.Date.
+func At(date, clock string, loc *time.Location) (time.Time, error) {
+	day, err := stringToTime(date, layout)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date: %s", date)
+	}
+	var hour, minute int
+	if clock != "" {
+		t, err := time.Parse(clockLayout, clock)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time: %s", clock)
+		}
+		hour, minute = t.Hour(), t.Minute()
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, loc), nil
+}
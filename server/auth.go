@@ -0,0 +1,156 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Вход по паролю из TODO_PASSWORD. /api/signin выдаёт токен JWT,
+// который клиент передаёт в куке token. Токен подписан HMAC-SHA256
+// с паролем в качестве ключа, поэтому после смены пароля старые
+// токены перестают подходить.
+
+// Время жизни токена, как у куки, которую ставит фронтенд
+const tokenTTL = 8 * time.Hour
+
+const tokenCookie = "token"
+
+var (
+	errTokenInvalid = errors.New("invalid token")
+	errTokenExpired = errors.New("token expired")
+)
+
+// Auth — проверка пароля и токенов. Пустой пароль отключает аутентификацию.
+type Auth struct {
+	password string
+}
+
+// NewAuth возвращает проверку для пароля password
+func NewAuth(password string) *Auth {
+	return &Auth{password: password}
+}
+
+// Enabled сообщает, что для доступа к задачам нужен пароль
+func (a *Auth) Enabled() bool {
+	return a.password != ""
+}
+
+// Заголовок токена всегда один и тот же
+var tokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+type tokenClaims struct {
+	IssuedAt  int64 `json:"iat"`
+	ExpiresAt int64 `json:"exp"`
+}
+
+// sign возвращает подпись части токена до последней точки
+func (a *Auth) sign(unsigned string) string {
+	mac := hmac.New(sha256.New, []byte(a.password))
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// NewToken выдаёт токен на tokenTTL
+func (a *Auth) NewToken() (string, error) {
+	now := time.Now()
+	payload, err := json.Marshal(tokenClaims{
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(tokenTTL).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := tokenHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + a.sign(unsigned), nil
+}
+
+// Verify проверяет подпись и срок действия токена
+func (a *Auth) Verify(token string) error {
+	header, rest, ok := strings.Cut(token, ".")
+	if !ok || header != tokenHeader {
+		return errTokenInvalid
+	}
+	payload, signature, ok := strings.Cut(rest, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(a.sign(header+"."+payload))) {
+		return errTokenInvalid
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return errTokenInvalid
+	}
+	var claims tokenClaims
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return errTokenInvalid
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return errTokenExpired
+	}
+	return nil
+}
+
+// SigninHandler проверяет пароль из {"password": "..."} и возвращает {"token": "..."}.
+// Токен также ставится в куку token.
+func (a *Auth) SigninHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	var request struct {
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if !a.Enabled() {
+		respondWithError(w, http.StatusBadRequest, "Authentication is disabled")
+		return
+	}
+	if !hmac.Equal([]byte(request.Password), []byte(a.password)) {
+		respondWithError(w, http.StatusUnauthorized, "Wrong password")
+		return
+	}
+
+	token, err := a.NewToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create token")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     tokenCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  time.Now().Add(tokenTTL),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"token": token})
+}
+
+// Middleware пропускает запрос к next только с действующим токеном в куке token,
+// иначе отвечает 401 с ошибкой в JSON
+func (a *Auth) Middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !a.Enabled() {
+			next(w, r)
+			return
+		}
+		cookie, err := r.Cookie(tokenCookie)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "Authentication required")
+			return
+		}
+		if err := a.Verify(cookie.Value); err != nil {
+			respondWithError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		next(w, r)
+	}
+}
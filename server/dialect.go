@@ -0,0 +1,49 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+)
+
+// dialect — особенности SQL базы, в которой хранятся задачи
+type dialect struct {
+	// numbered — параметры запроса вида $1, $2 вместо ?
+	numbered bool
+	// fts — для поиска есть полнотекстовый индекс scheduler_fts
+	fts bool
+	// like — оператор поиска подстроки без учёта регистра
+	like string
+	// lower — функция перевода текста в нижний регистр для сравнения через like,
+	// если like сам не учитывает регистр букв вне ASCII
+	lower string
+	// returning — id новой задачи возвращается через INSERT ... RETURNING id
+	returning bool
+}
+
+var (
+	sqliteDialect   = dialect{fts: true, like: "LIKE", lower: sqliteLower}
+	postgresDialect = dialect{numbered: true, like: "ILIKE", returning: true}
+)
+
+// rebind заменяет параметры ? в запросе на параметры диалекта.
+// Знаки ? внутри строк в кавычках не заменяются.
+func (d dialect) rebind(query string) string {
+	if !d.numbered {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	quoted := false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == '?' && !quoted:
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,22 @@
+package server
+
+import "net/http"
+
+// NewHandler возвращает обработчик всех запросов сервера: API задач
+// на хранилище store и файлы фронтенда из директории webDir.
+// Если задан password, API задач (/api/task*) доступно только после входа.
+func NewHandler(store TaskStore, webDir, password string) http.Handler {
+	s := New(store)
+	auth := NewAuth(password)
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.Dir(webDir)))
+	mux.HandleFunc("/api/signin", auth.SigninHandler)
+	mux.HandleFunc("/api/task", auth.Middleware(s.TaskHandler))
+	mux.HandleFunc("/api/tasks", auth.Middleware(s.GetAllTasksHandler))
+	mux.HandleFunc("/api/nextdate", ApiNextDateHandler)
+	mux.HandleFunc("/api/occurrences", ApiOccurrencesHandler)
+	mux.HandleFunc("/api/parse", ApiParseHandler)
+	mux.HandleFunc("/api/task/done", auth.Middleware(s.MarkAsDone))
+	mux.HandleFunc("/api/task/skip", auth.Middleware(s.SkipOccurrence))
+	return mux
+}
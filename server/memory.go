@@ -0,0 +1,264 @@
+package server
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore — хранилище задач в памяти для тестов и демонстраций.
+// Задачи пропадают при остановке сервера. Поиск по тексту ищет подстроки
+// без учёта регистра и, в отличие от SQLite, не сортирует по релевантности.
+type MemoryStore struct {
+	mu     sync.Mutex
+	tasks  map[int64]Task
+	lastID int64
+}
+
+// NewMemoryStore возвращает пустое хранилище в памяти
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tasks: make(map[int64]Task)}
+}
+
+func (s *MemoryStore) AddTask(task Task) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastID++
+	task.ID = strconv.FormatInt(s.lastID, 10)
+	task.DoneCount = 0
+	task.RepeatText = ""
+	s.tasks[s.lastID] = task
+	return task.ID, nil
+}
+
+func (s *MemoryStore) GetTask(id string) (Task, error) {
+	taskID, err := parseTaskID(id)
+	if err != nil {
+		return Task{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, ok := s.tasks[taskID]
+	if !ok {
+		return Task{}, ErrTaskNotFound
+	}
+	return task, nil
+}
+
+func (s *MemoryStore) UpdateTask(task Task) error {
+	taskID, err := parseTaskID(task.ID)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.tasks[taskID]
+	if !ok {
+		return ErrTaskNotFound
+	}
+	task.DoneCount = stored.DoneCount
+	task.RepeatText = ""
+	s.tasks[taskID] = task
+	return nil
+}
+
+func (s *MemoryStore) DeleteTask(id string) error {
+	taskID, err := parseTaskID(id)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.tasks[taskID]; !ok {
+		return ErrTaskNotFound
+	}
+	delete(s.tasks, taskID)
+	return nil
+}
+
+func (s *MemoryStore) RescheduleTask(id, date, clock string, completed bool) error {
+	taskID, err := parseTaskID(id)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, ok := s.tasks[taskID]
+	if !ok {
+		return ErrTaskNotFound
+	}
+	task.Date, task.Time = date, clock
+	if completed {
+		task.DoneCount++
+	}
+	s.tasks[taskID] = task
+	return nil
+}
+
+func (s *MemoryStore) ListTasks(filter TaskFilter) (TaskList, error) {
+	// Порядок и курсор — те же, что у SQL-хранилищ
+	q := taskQuery{limit: filter.Limit}
+	if filter.Sort != "" {
+		q.order = []sortKey{{expr: filter.Sort, desc: filter.Desc}}
+	}
+	keys := q.keys()
+	var after []any
+	if filter.Cursor != "" {
+		values, err := decodeCursor(filter.Cursor)
+		if err == nil && !cursorMatches(keys, values) {
+			err = errors.New("cursor does not match sort order")
+		}
+		if err != nil {
+			return TaskList{}, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+		}
+		after = values
+	}
+
+	s.mu.Lock()
+	var tasks []Task
+	for _, task := range s.tasks {
+		if filter.matches(task) {
+			tasks = append(tasks, task)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(tasks, func(i, j int) bool {
+		return compareKeys(keys, keyValues(keys, tasks[i]), keyValues(keys, tasks[j])) < 0
+	})
+
+	list := TaskList{Tasks: []Task{}}
+	for _, task := range tasks {
+		values := keyValues(keys, task)
+		if after != nil && compareKeys(keys, values, after) <= 0 {
+			continue
+		}
+		if len(list.Tasks) == q.limit {
+			// Есть ещё задачи: курсор указывает на последнюю задачу страницы
+			cursor, err := encodeCursor(keyValues(keys, list.Tasks[len(list.Tasks)-1]))
+			if err != nil {
+				return TaskList{}, err
+			}
+			list.NextCursor = cursor
+			break
+		}
+		list.Tasks = append(list.Tasks, task)
+	}
+	return list, nil
+}
+
+// Close ничего не делает: хранилищу в памяти нечего закрывать
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// matches сообщает, подходит ли задача под условия фильтра
+func (f TaskFilter) matches(task Task) bool {
+	if f.Search != "" {
+		if date, err := time.Parse(searchDateLayout, f.Search); err == nil {
+			if task.Date != date.Format(layout) {
+				return false
+			}
+		} else {
+			for _, word := range strings.Fields(f.Search) {
+				if !taskContains(task, word) {
+					return false
+				}
+			}
+		}
+	}
+	if f.Contains != "" && !taskContains(task, f.Contains) {
+		return false
+	}
+	if f.From != "" && task.Date < f.From {
+		return false
+	}
+	if f.To != "" && task.Date > f.To {
+		return false
+	}
+	if f.Recurring != nil && *f.Recurring != (task.Repeat != "") {
+		return false
+	}
+	if f.Overdue != nil && *f.Overdue != f.overdue(task) {
+		return false
+	}
+	return true
+}
+
+// overdue сообщает, что дата задачи, а если задано время — его момент,
+// уже прошли к моменту f.Now в часовом поясе задачи
+func (f TaskFilter) overdue(task Task) bool {
+	today, clock := zoneNow(f.Now, task.TZ)
+	return task.Date < today || (task.Date == today && task.Time != "" && task.Time <= clock)
+}
+
+// taskContains сообщает, что заголовок или комментарий задачи
+// содержат подстроку text без учёта регистра
+func taskContains(task Task, text string) bool {
+	text = strings.ToLower(text)
+	return strings.Contains(strings.ToLower(task.Title), text) ||
+		strings.Contains(strings.ToLower(task.Comment), text)
+}
+
+// keyValues возвращает значения ключей сортировки задачи в том же виде,
+// что и в курсоре SQL-хранилищ
+func keyValues(keys []sortKey, task Task) []any {
+	values := make([]any, len(keys))
+	for i, key := range keys {
+		switch key.expr {
+		case "date":
+			values[i] = task.Date
+		case "title":
+			values[i] = task.Title
+		case "id":
+			id, _ := strconv.ParseInt(task.ID, 10, 64)
+			values[i] = id
+		}
+	}
+	return values
+}
+
+// cursorMatches сообщает, что значения из курсора подходят к ключам сортировки
+func cursorMatches(keys []sortKey, values []any) bool {
+	if len(values) != len(keys) {
+		return false
+	}
+	for i, key := range keys {
+		var ok bool
+		if key.expr == "id" {
+			_, ok = values[i].(int64)
+		} else {
+			_, ok = values[i].(string)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// compareKeys сравнивает значения ключей сортировки двух задач с учётом
+// направления каждого ключа
+func compareKeys(keys []sortKey, a, b []any) int {
+	for i, key := range keys {
+		var c int
+		switch x := a[i].(type) {
+		case string:
+			c = strings.Compare(x, b[i].(string))
+		case int64:
+			c = cmp.Compare(x, b[i].(int64))
+		}
+		if key.desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
@@ -0,0 +1,229 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// sortKey — выражение, по которому сортируется список задач
+type sortKey struct {
+	expr string
+	desc bool
+}
+
+// taskQuery — части запроса списка задач
+type taskQuery struct {
+	join     string
+	joinArgs []any
+	where    []string
+	args     []any
+	// Ключи сортировки, по умолчанию дата. Последним всегда добавляется id,
+	// чтобы порядок был однозначным и по нему можно было продолжить список.
+	order   []sortKey
+	limit   int
+	dialect dialect
+}
+
+// newTaskQuery собирает запрос списка задач по фильтру для базы с диалектом d.
+// zones — часовые пояса задач в базе, нужны для условия Overdue.
+func newTaskQuery(filter TaskFilter, d dialect, zones []string) (taskQuery, error) {
+	q := taskQuery{limit: filter.Limit, dialect: d}
+	q.search(filter.Search)
+	if filter.Contains != "" {
+		q.contains(filter.Contains)
+	}
+	if filter.From != "" {
+		q.where = append(q.where, "date >= ?")
+		q.args = append(q.args, filter.From)
+	}
+	if filter.To != "" {
+		q.where = append(q.where, "date <= ?")
+		q.args = append(q.args, filter.To)
+	}
+	if filter.Recurring != nil {
+		if *filter.Recurring {
+			q.where = append(q.where, "repeat <> ''")
+		} else {
+			q.where = append(q.where, "repeat = ''")
+		}
+	}
+	if filter.Overdue != nil {
+		q.overdue(filter.Now, *filter.Overdue, zones)
+	}
+	if filter.Sort != "" {
+		q.order = []sortKey{{expr: filter.Sort, desc: filter.Desc}}
+	}
+
+	// Курсор разбирается последним: он зависит от порядка сортировки
+	if err := q.after(filter.Cursor); err != nil {
+		return taskQuery{}, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	return q, nil
+}
+
+// overdue добавляет в запрос условие на задачи, дата и время которых
+// прошли к моменту now (или, если !overdue, ещё не прошли). Сегодняшняя
+// дата и время у каждого часового пояса свои, поэтому условие
+// строится отдельно для каждого пояса из zones.
+func (q *taskQuery) overdue(now time.Time, overdue bool, zones []string) {
+	// Без задач условие ложно, а его отрицание истинно
+	parts := []string{"1 = 0"}
+	for _, tz := range zones {
+		today, clock := zoneNow(now, tz)
+		parts = append(parts, "(tz = ? AND (date < ? OR (date = ? AND time <> '' AND time <= ?)))")
+		q.args = append(q.args, tz, today, today, clock)
+	}
+	cond := "(" + strings.Join(parts, " OR ") + ")"
+	if !overdue {
+		cond = "NOT " + cond
+	}
+	q.where = append(q.where, cond)
+}
+
+// keys возвращает ключи сортировки вместе с id
+func (q taskQuery) keys() []sortKey {
+	order := q.order
+	if len(order) == 0 {
+		order = []sortKey{{expr: "date"}}
+	}
+	last := order[len(order)-1]
+	if last.expr == "id" {
+		return order
+	}
+	return append(append([]sortKey(nil), order...), sortKey{expr: "id", desc: last.desc})
+}
+
+// after добавляет в запрос условие, с которого продолжается список.
+// cursor — значения ключей сортировки последней задачи предыдущей страницы.
+func (q *taskQuery) after(cursor string) error {
+	if cursor == "" {
+		return nil
+	}
+	values, err := decodeCursor(cursor)
+	if err != nil {
+		return err
+	}
+	keys := q.keys()
+	if len(values) != len(keys) {
+		return errors.New("cursor does not match sort order")
+	}
+
+	// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ...
+	var alternatives []string
+	var args []any
+	for i, key := range keys {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, keys[j].expr+" = ?")
+			args = append(args, values[j])
+		}
+		op := " > ?"
+		if key.desc {
+			op = " < ?"
+		}
+		parts = append(parts, key.expr+op)
+		args = append(args, values[i])
+		alternatives = append(alternatives, "("+strings.Join(parts, " AND ")+")")
+	}
+	q.where = append(q.where, "("+strings.Join(alternatives, " OR ")+")")
+	q.args = append(q.args, args...)
+	return nil
+}
+
+// sql возвращает текст запроса и его параметры. Кроме колонок задачи
+// выбираются значения ключей сортировки для курсора, а строк выбирается
+// на одну больше limit, чтобы узнать, есть ли следующая страница.
+func (q taskQuery) sql() (string, []any) {
+	keys := q.keys()
+	exprs := make([]string, 0, len(keys))
+	order := make([]string, 0, len(keys))
+	for _, key := range keys {
+		exprs = append(exprs, key.expr)
+		if key.desc {
+			order = append(order, key.expr+" DESC")
+		} else {
+			order = append(order, key.expr+" ASC")
+		}
+	}
+
+	query := "SELECT " + taskColumns + ", " + strings.Join(exprs, ", ") + " FROM scheduler"
+	if q.join != "" {
+		query += " " + q.join
+	}
+	if len(q.where) > 0 {
+		query += " WHERE " + strings.Join(q.where, " AND ")
+	}
+	query += " ORDER BY " + strings.Join(order, ", ") + " LIMIT ?"
+	args := append(append([]any(nil), q.joinArgs...), q.args...)
+	return q.dialect.rebind(query), append(args, q.limit+1)
+}
+
+// list выполняет запрос и возвращает страницу задач
+func (q taskQuery) list(db *sql.DB) (TaskList, error) {
+	query, args := q.sql()
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return TaskList{}, err
+	}
+	defer rows.Close()
+
+	list := TaskList{Tasks: []Task{}}
+	var last []any
+	for rows.Next() {
+		var task Task
+		values := make([]any, len(q.keys()))
+		fields := taskFields(&task)
+		for i := range values {
+			fields = append(fields, &values[i])
+		}
+		if err := rows.Scan(fields...); err != nil {
+			return TaskList{}, err
+		}
+		if len(list.Tasks) == q.limit {
+			// Есть ещё задачи: курсор указывает на последнюю задачу страницы
+			list.NextCursor, err = encodeCursor(last)
+			if err != nil {
+				return TaskList{}, err
+			}
+			break
+		}
+		list.Tasks = append(list.Tasks, task)
+		last = values
+	}
+	return list, rows.Err()
+}
+
+// encodeCursor возвращает курсор со значениями ключей сортировки задачи
+func encodeCursor(values []any) (string, error) {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor возвращает значения ключей сортировки из курсора
+func decodeCursor(cursor string) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+	var values []any
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	for i, value := range values {
+		// Целые числа (id) возвращаются целыми: PostgreSQL не сравнивает
+		// bigint с параметром с плавающей точкой
+		if f, ok := value.(float64); ok && f == math.Trunc(f) {
+			values[i] = int64(f)
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Поиск задач для /api/tasks?search=. Строка вида 02.01.2006 ищет задачи
+// на эту дату, остальные строки ищутся как подстроки в заголовке
+// и комментарии. В SQLite поиск идёт по полнотекстовому индексу scheduler_fts
+// (FTS5 с токенизатором trigram), который обновляют триггеры базы,
+// и задачи сортируются по релевантности,
+// в PostgreSQL — через ILIKE по самой таблице.
+
+const searchDateLayout = "02.01.2006"
+
+// Индекс trigram находит только подстроки не короче трёх символов,
+// более короткие слова ищутся через LIKE
+const minIndexedWord = 3
+
+// search добавляет в запрос условие поиска
+func (q *taskQuery) search(text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	if date, err := time.Parse(searchDateLayout, text); err == nil {
+		q.where = append(q.where, "date = ?")
+		q.args = append(q.args, date.Format(layout))
+		return
+	}
+
+	words := strings.Fields(text)
+	indexed := true
+	for _, word := range words {
+		if utf8.RuneCountInString(word) < minIndexedWord {
+			indexed = false
+		}
+	}
+	if indexed && q.dialect.fts {
+		// Слова в кавычках ищутся как подстроки, все сразу
+		terms := make([]string, 0, len(words))
+		for _, word := range words {
+			terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"`)
+		}
+		q.join = `JOIN (SELECT rowid AS match_id, bm25(scheduler_fts) AS relevance FROM scheduler_fts WHERE scheduler_fts MATCH ?) ON match_id = id`
+		q.joinArgs = append(q.joinArgs, strings.Join(terms, " "))
+		q.order = []sortKey{{expr: "relevance"}, {expr: "date"}}
+		return
+	}
+
+	for _, word := range words {
+		q.contains(word)
+	}
+}
+
+// contains добавляет в запрос условие: заголовок или комментарий
+// содержат подстроку text
+func (q *taskQuery) contains(text string) {
+	title, comment := "title", "comment"
+	if lower := q.dialect.lower; lower != "" {
+		title, comment = lower+"(title)", lower+"(comment)"
+		text = strings.ToLower(text)
+	}
+	pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(text) + "%"
+	like := q.dialect.like
+	q.where = append(q.where, `(`+title+` `+like+` ? ESCAPE '\' OR `+comment+` `+like+` ? ESCAPE '\')`)
+	q.args = append(q.args, pattern, pattern)
+}
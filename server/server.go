@@ -1,56 +1,103 @@
 package server
 
 import (
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/MirekKrassilnikov/go_final_project/natural"
 	"github.com/MirekKrassilnikov/go_final_project/repeater"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 )
 
 const layout = "20060102"
 
 type Task struct {
-	ID      int    `json:"id,omitempty"`
+	ID      string `json:"id,omitempty"`
 	Date    string `json:"date"`
 	Title   string `json:"title"`
 	Comment string `json:"comment"`
 	Repeat  string `json:"repeat"`
+	// Условия окончания повторений: последняя дата серии
+	// и максимальное количество выполнений
+	Until     string `json:"until,omitempty"`
+	MaxCount  int    `json:"max_count,omitempty"`
+	DoneCount int    `json:"done_count,omitempty"`
+	// Даты через запятую, в которые повторение пропускается
+	Exdates string `json:"exdates,omitempty"`
+	// Перенос даты с нерабочего дня: "next", "prev" или пустая строка
+	Shift string `json:"shift,omitempty"`
+	// Необязательное время в формате 15:04 и часовой пояс IANA,
+	// по умолчанию используется пояс сервера
+	Time string `json:"time,omitempty"`
+	TZ   string `json:"tz,omitempty"`
+	// Режим повторения: "" — по расписанию, "completion" — от дня выполнения
+	Mode string `json:"mode,omitempty"`
+	// Что делать с 29 февраля и 31 числом в коротких месяцах:
+	// "forward", "clamp", "skip" или пустая строка для поведения по умолчанию
+	Overflow string `json:"overflow,omitempty"`
+	// Первая дата серии, от которой отсчитывается расписание. Её ставит
+	// сервер при создании задачи и при смене даты или правила, клиенту
+	// она не передаётся.
+	Anchor string `json:"-"`
+	// Описание правила повторения для интерфейса, в базе не хранится
+	RepeatText string `json:"repeat_text,omitempty"`
+}
+
+// Колонки таблицы scheduler в порядке, который ожидает scanTask
+const taskColumns = "id, date, title, comment, repeat, until, max_count, done_count, exdates, shift, time, tz, mode, overflow, anchor"
+
+// taskFields возвращает указатели на поля задачи в порядке taskColumns
+func taskFields(task *Task) []any {
+	return []any{&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat,
+		&task.Until, &task.MaxCount, &task.DoneCount, &task.Exdates, &task.Shift, &task.Time, &task.TZ, &task.Mode, &task.Overflow, &task.Anchor}
+}
+
+// scanTask читает задачу из результата запроса по колонкам taskColumns
+func scanTask(row interface{ Scan(...any) error }) (Task, error) {
+	var task Task
+	err := row.Scan(taskFields(&task)...)
+	return task, err
 }
 
 type Response struct {
-	ID    int    `json:"id,omitempty"`
+	ID    string `json:"id,omitempty"`
 	Error string `json:"error,omitempty"`
 }
 
-func TaskHandler(w http.ResponseWriter, r *http.Request) {
-	db, err := sql.Open("sqlite3", "../scheduler.db")
-	if err != nil {
-		http.Error(w, `{"error":"Failed to connect to database"}`, http.StatusInternalServerError)
-		return
-	}
-	defer db.Close()
+// Server — обработчики API задач, работающие с хранилищем
+type Server struct {
+	store TaskStore
+}
+
+// New возвращает обработчики API, работающие с хранилищем store
+func New(store TaskStore) *Server {
+	return &Server{store: store}
+}
+
+func (s *Server) TaskHandler(w http.ResponseWriter, r *http.Request) {
 	idStr := r.URL.Query().Get("id")
 	switch r.Method {
 	case http.MethodPost:
-		HandlePost(w, r) // Обработка POST-запросов
+		s.HandlePost(w, r) // Обработка POST-запросов
 
 	case http.MethodGet:
-		getTaskById(w, db, idStr) // Обработка GET-запросов
+		s.getTaskById(w, idStr, requestLanguage(r)) // Обработка GET-запросов
 	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	case http.MethodPut:
-		UpdateTask(w, r)
+		s.UpdateTask(w, r)
 
 	case http.MethodDelete:
-		DeleteTaskByID(w, r)
+		s.DeleteTaskByID(w, r)
 	}
 
 }
 
-func UpdateTask(w http.ResponseWriter, r *http.Request) {
+func (s *Server) UpdateTask(w http.ResponseWriter, r *http.Request) {
 	var task Task
 	decoder := json.NewDecoder(r.Body)
 	err := decoder.Decode(&task)
@@ -61,7 +108,7 @@ func UpdateTask(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
 	// Проверка обязательного поля id и title
-	if task.ID == 0 {
+	if task.ID == "" {
 		http.Error(w, `{"error":"ID is required"}`, http.StatusBadRequest)
 		return
 	}
@@ -70,36 +117,36 @@ func UpdateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Проверка формата даты
-	_, err = time.Parse(layout, task.Date)
-	if err != nil {
-		http.Error(w, `{"error":"Invalid date format"}`, http.StatusBadRequest)
+	// Счётчик выполнений не приходит от клиента, поэтому
+	// для проверки ограничения берётся сохранённый
+	stored, err := s.store.GetTask(task.ID)
+	if errors.Is(err, ErrTaskNotFound) {
+		http.Error(w, `{"error":"Task not found"}`, http.StatusNotFound)
 		return
+	} else if err != nil {
+		http.Error(w, `{"error":"Failed to update task"}`, http.StatusInternalServerError)
+		return
+	}
+	task.DoneCount = stored.DoneCount
+	// Серия продолжается, пока не изменились её дата и правило
+	if task.Date == stored.Date && task.Repeat == stored.Repeat {
+		task.Anchor = stored.Anchor
+	} else {
+		task.Anchor = ""
 	}
 
-	// Подключаемся к базе данных
-	db, err := sql.Open("sqlite3", "../scheduler.db")
-	if err != nil {
-		http.Error(w, `{"error":"Failed to connect to database"}`, http.StatusInternalServerError)
+	// Проверка даты и правила повторения
+	if msg := checkTaskDate(&task); msg != "" {
+		http.Error(w, `{"error":"`+msg+`"}`, http.StatusBadRequest)
 		return
 	}
-	defer db.Close()
 
-	// Проверяем, существует ли задача
-	var existingTask Task
-	err = db.QueryRow("SELECT id FROM scheduler WHERE id = ?", task.ID).Scan(&existingTask.ID)
-	if err == sql.ErrNoRows {
+	// Выполняем обновление задачи
+	err = s.store.UpdateTask(task)
+	if errors.Is(err, ErrTaskNotFound) {
 		http.Error(w, `{"error":"Task not found"}`, http.StatusNotFound)
 		return
 	} else if err != nil {
-		http.Error(w, `{"error":"Server error"}`, http.StatusInternalServerError)
-		return
-	}
-
-	// Выполняем обновление задачи
-	updateSQL := `UPDATE scheduler SET date = ?, title = ?, comment = ?, repeat = ? WHERE id = ?`
-	_, err = db.Exec(updateSQL, task.Date, task.Title, task.Comment, task.Repeat, task.ID)
-	if err != nil {
 		http.Error(w, `{"error":"Failed to update task"}`, http.StatusInternalServerError)
 		return
 	}
@@ -110,6 +157,177 @@ func UpdateTask(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("{}"))
 }
 
+// checkTaskDate проверяет дату и правило повторения задачи.
+// Пустая дата заменяется на сегодняшнюю, а прошедшая — на сегодняшнюю
+// или на следующую дату по правилу повторения. Правило сохраняется
+// в каноническом виде.
+// Возвращает текст ошибки или пустую строку, если задача корректна.
+func checkTaskDate(task *Task) string {
+	// Сегодняшняя дата определяется в часовом поясе задачи
+	loc, err := taskLocation(*task)
+	if err != nil {
+		return "Invalid time zone"
+	}
+	today := repeater.Today(loc)
+	if task.Date == "" {
+		task.Date = today
+	}
+	if _, err := time.Parse(layout, task.Date); err != nil {
+		return "Invalid date format"
+	}
+	// Задача в прошлом, если прошла её дата, а если задано время — её момент
+	past := task.Date < today
+	if task.Time != "" {
+		at, err := repeater.At(task.Date, task.Time, loc)
+		if err != nil {
+			return "Invalid time format"
+		}
+		past = at.Before(time.Now())
+	}
+
+	if task.Repeat == "" {
+		task.Anchor = ""
+		if task.Until != "" || task.MaxCount != 0 || task.Exdates != "" || task.Shift != "" || task.Mode != "" || task.Overflow != "" {
+			return "End conditions, exception dates, shift, mode and overflow require a repeat rule"
+		}
+		if task.Date < today {
+			task.Date = today
+		}
+		return ""
+	}
+
+	// Правило проверяется всегда, даже если дата ещё не наступила
+	rule, err := repeater.Parse(task.Repeat)
+	if err != nil {
+		return "Invalid repeat rule"
+	}
+	task.Repeat = rule.String()
+	// Новая серия начинается с даты, которую задал пользователь,
+	// даже если она уже прошла
+	if task.Anchor == "" {
+		task.Anchor = task.Date
+	}
+	if rule.IsSubDaily() && task.Time == "" {
+		return "Time is required for hourly and minute repeat rules"
+	}
+	if task.Until != "" {
+		if _, err := time.Parse(layout, task.Until); err != nil {
+			return "Invalid until date format"
+		}
+	}
+	if task.MaxCount < 0 || task.MaxCount > repeater.MaxCount {
+		return fmt.Sprintf("Max count must be between 0 and %d", repeater.MaxCount)
+	}
+	// Выполненные даты уже израсходовали такое ограничение
+	if task.MaxCount != 0 && task.MaxCount <= task.DoneCount {
+		return "Max count must be greater than done count"
+	}
+	exdates, err := repeater.ParseDates(task.Exdates)
+	if err != nil {
+		return "Invalid exception date format"
+	}
+	task.Exdates = repeater.JoinDates(exdates)
+	if _, err := repeater.ParseShift(task.Shift); err != nil {
+		return "Invalid shift mode"
+	}
+	if _, err := repeater.ParseMode(task.Mode); err != nil {
+		return "Invalid recurrence mode"
+	}
+	if _, err := repeater.ParseOverflow(task.Overflow); err != nil {
+		return "Invalid overflow policy"
+	}
+
+	// Прошедшая или исключённая дата заменяется следующей по правилу
+	if past || slices.Contains(strings.Split(task.Exdates, ","), task.Date) {
+		next, err := taskSeries(*task, loc).NextAt(time.Now())
+		if err != nil {
+			return "Repeat rule has no more occurrences"
+		}
+		setTaskMoment(task, next)
+	}
+	return ""
+}
+
+// setTaskMoment записывает в задачу дату и, если задача со временем, время
+func setTaskMoment(task *Task, moment time.Time) {
+	task.Date = moment.Format(layout)
+	if task.Time != "" {
+		task.Time = moment.Format("15:04")
+	}
+}
+
+// taskLocation возвращает часовой пояс задачи
+func taskLocation(task Task) (*time.Location, error) {
+	if task.TZ == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(task.TZ)
+}
+
+// taskSeries возвращает серию повторений задачи, начатую с её текущей даты
+func taskSeries(task Task, loc *time.Location) repeater.Series {
+	return repeater.Series{
+		Date:     task.Date,
+		Anchor:   task.Anchor,
+		Repeat:   task.Repeat,
+		Until:    task.Until,
+		Count:    remainingCount(task),
+		Except:   task.Exdates,
+		Shift:    task.Shift,
+		Time:     task.Time,
+		Location: loc,
+		Mode:     task.Mode,
+		Overflow: task.Overflow,
+	}
+}
+
+// describeRepeat заполняет описание правила повторения задачи на языке lang
+func describeRepeat(task *Task, lang repeater.Language) {
+	if task.Repeat == "" {
+		return
+	}
+	rule, err := repeater.Series{
+		Repeat:   task.Repeat,
+		Until:    task.Until,
+		Count:    task.MaxCount,
+		Except:   task.Exdates,
+		Shift:    task.Shift,
+		Overflow: task.Overflow,
+	}.Rule()
+	if err != nil {
+		return
+	}
+	if date, err := time.Parse(layout, task.Date); err == nil {
+		rule = rule.From(date)
+	}
+	task.RepeatText = rule.Describe(lang)
+}
+
+// requestLanguage возвращает язык описаний из параметра lang
+// или заголовка Accept-Language
+func requestLanguage(r *http.Request) repeater.Language {
+	if lang := r.FormValue("lang"); lang != "" {
+		return repeater.ParseLanguage(lang)
+	}
+	lang, _, _ := strings.Cut(r.Header.Get("Accept-Language"), ",")
+	lang, _, _ = strings.Cut(lang, ";")
+	return repeater.ParseLanguage(lang)
+}
+
+// remainingCount возвращает, сколько дат осталось в серии задачи,
+// включая текущую. Ноль означает, что количество не ограничено.
+func remainingCount(task Task) int {
+	if task.MaxCount == 0 {
+		return 0
+	}
+	// checkTaskDate такого не пропускает, но текущая дата задачи
+	// в любом случае остаётся последней
+	if task.DoneCount >= task.MaxCount {
+		return 1
+	}
+	return task.MaxCount - task.DoneCount
+}
+
 /*
 	func HandleGet(w http.ResponseWriter, r *http.Request) {
 		db, err := sql.Open("sqlite3", "scheduler.db")
@@ -128,49 +346,39 @@ func UpdateTask(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 */
-func GetAllTasksHandler(w http.ResponseWriter, r *http.Request) {
-	db, err := sql.Open("sqlite3", "../scheduler.db")
-	if err != nil {
-		http.Error(w, `{"error":"Failed to connect to database"}`, http.StatusInternalServerError)
+func (s *Server) GetAllTasksHandler(w http.ResponseWriter, r *http.Request) {
+	filter, msg := parseTaskFilter(r)
+	if msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
 		return
 	}
-	defer db.Close()
-
-	rows, err := db.Query("SELECT id, date, title, comment, repeat FROM scheduler ORDER BY date ASC")
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	list, err := s.store.ListTasks(filter)
+	if errors.Is(err, ErrInvalidCursor) {
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	} else if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load tasks")
 		return
 	}
-	defer rows.Close()
 
-	var tasks []Task
-	for rows.Next() {
-		var task Task
-		err := rows.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		tasks = append(tasks, task)
+	lang := requestLanguage(r)
+	for i := range list.Tasks {
+		describeRepeat(&list.Tasks[i], lang)
 	}
-
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(tasks)
+	json.NewEncoder(w).Encode(list)
 }
 
-func getTaskById(w http.ResponseWriter, db *sql.DB, idStr string) {
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		http.Error(w, "invalid id parameter", http.StatusBadRequest)
+func (s *Server) getTaskById(w http.ResponseWriter, idStr string, lang repeater.Language) {
+	if idStr == "" {
+		http.Error(w, `{"error":"ID is required"}`, http.StatusBadRequest)
 		return
 	}
 
-	// Выполняем SQL-запрос для получения задачи по id
-	var task Task
-	err = db.QueryRow("SELECT id, date, title, comment, repeat FROM scheduler WHERE id = ?", id).
-		Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat)
+	// Получаем задачу по id
+	task, err := s.store.GetTask(idStr)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, ErrTaskNotFound) {
 			http.Error(w, `{"error":"task not found"}`, http.StatusNotFound)
 		} else {
 			http.Error(w, `{"error":"server error"}`, http.StatusInternalServerError)
@@ -178,6 +386,8 @@ func getTaskById(w http.ResponseWriter, db *sql.DB, idStr string) {
 		return
 	}
 
+	describeRepeat(&task, lang)
+
 	// Устанавливаем заголовок Content-Type для JSON
 	w.Header().Set("Content-Type", "application/json")
 
@@ -185,7 +395,7 @@ func getTaskById(w http.ResponseWriter, db *sql.DB, idStr string) {
 	json.NewEncoder(w).Encode(task)
 }
 
-func HandlePost(w http.ResponseWriter, r *http.Request) {
+func (s *Server) HandlePost(w http.ResponseWriter, r *http.Request) {
 	var task Task
 	decoder := json.NewDecoder(r.Body)
 	err := decoder.Decode(&task)
@@ -201,45 +411,18 @@ func HandlePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Проверка формата даты и установка текущей даты, если дата некорректна
-	timeTimeDate, err := time.Parse(layout, task.Date)
-	if err != nil {
-		http.Error(w, `{"error":"Invalid date format"}`, http.StatusBadRequest)
-		return
-	}
-	if timeTimeDate.Before(time.Now()) {
-		// Если дата задачи меньше текущей даты и есть правило повторения
-		if task.Repeat != "" {
-			nextDate, err := repeater.NextDate(time.Now().Format(layout), task.Date, task.Repeat)
-			if err != nil {
-				http.Error(w, `{"error":"Invalid repeat rule"}`, http.StatusBadRequest)
-				return
-			}
-			task.Date = nextDate
-		} else {
-			task.Date = time.Now().Format(layout)
-		}
-	}
-	db, err := sql.Open("sqlite3", "../scheduler.db")
-	if err != nil {
-		http.Error(w, `{"error":"Failed to connect to database"}`, http.StatusInternalServerError)
+	// Проверка даты и правила повторения
+	if msg := checkTaskDate(&task); msg != "" {
+		http.Error(w, `{"error":"`+msg+`"}`, http.StatusBadRequest)
 		return
 	}
-	defer db.Close()
-
-	insertSQL := `INSERT INTO scheduler (date, title, comment, repeat) VALUES (?, ?, ?, ?);`
-	result, err := db.Exec(insertSQL, task.Date, task.Title, task.Comment, task.Repeat)
+	id, err := s.store.AddTask(task)
 	if err != nil {
 		http.Error(w, `{"error":"Failed to insert task"}`, http.StatusInternalServerError)
 		return
 	}
-	id, err := result.LastInsertId()
-	if err != nil {
-		http.Error(w, `{"error":"Failed to retrieve task ID"}`, http.StatusInternalServerError)
-		return
-	}
 	response := Response{
-		ID: int(id),
+		ID: id,
 	}
 	responseData, err := json.Marshal(response)
 	if err != nil {
@@ -258,87 +441,108 @@ func MainHandle(res http.ResponseWriter, req *http.Request) {
 	res.Write([]byte(out))
 }
 
-func MarkAsDone(w http.ResponseWriter, r *http.Request) {
+// MarkAsDone отмечает задачу выполненной: разовая задача удаляется,
+// а повторяющаяся переносится на следующую дату
+func (s *Server) MarkAsDone(w http.ResponseWriter, r *http.Request) {
+	s.advanceTask(w, r, true)
+}
+
+// SkipOccurrence пропускает текущую дату повторяющейся задачи и переносит
+// её на следующую, не засчитывая выполнение
+func (s *Server) SkipOccurrence(w http.ResponseWriter, r *http.Request) {
+	s.advanceTask(w, r, false)
+}
+
+// advanceTask переносит задачу на следующую дату серии или удаляет её,
+// если серия закончилась. При completed выполнение засчитывается.
+func (s *Server) advanceTask(w http.ResponseWriter, r *http.Request, completed bool) {
 	id := r.FormValue("id")
-	db, err := sql.Open("sqlite3", "../scheduler.db")
-	if err != nil {
-		http.Error(w, `{"error":"Failed to connect to database"}`, http.StatusInternalServerError)
+	if id == "" {
+		http.Error(w, `{"error":"ID is required"}`, http.StatusBadRequest)
 		return
 	}
-	defer db.Close()
-	var task Task
-	err = db.QueryRow("SELECT id, date, title, comment, repeat FROM scheduler WHERE id = ?", id).
-		Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat)
+	task, err := s.store.GetTask(id)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, ErrTaskNotFound) {
 			http.Error(w, `{"error":"task not found"}`, http.StatusNotFound)
 		} else {
 			http.Error(w, `{"error":"server error"}`, http.StatusInternalServerError)
 		}
 		return
 	}
+	if !completed && task.Repeat == "" {
+		http.Error(w, `{"error":"Only recurring tasks can be skipped"}`, http.StatusBadRequest)
+		return
+	}
 
-	if task.Repeat == "" {
-		err = DeleteTaskByID(w, r)
+	var next time.Time
+	if task.Repeat != "" {
+		loc, err := taskLocation(task)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			respondWithError(w, http.StatusInternalServerError, err.Error())
+			http.Error(w, `{"error":"Invalid time zone"}`, http.StatusInternalServerError)
+			return
+		}
+		// Выполнение в режиме "completion" отсчитывает следующую дату
+		// от сегодняшнего дня, пропуск — всегда по расписанию
+		series := taskSeries(task, loc)
+		if completed {
+			next, err = series.Done(time.Now())
+		} else {
+			next, err = series.NextAt(time.Now())
+		}
+		if err != nil && !errors.Is(err, repeater.ErrNoMoreOccurrences) {
+			http.Error(w, `{"error":"Error with calculating next date"}`, http.StatusInternalServerError)
 			return
 		}
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{}`))
-		return
-	}
-	now := time.Now().Format("20060102")
-	nextDate, err := repeater.NextDate(now, task.Date, task.Repeat)
-	if err != nil {
-		http.Error(w, "Error with calculating next date", http.StatusInternalServerError)
-		return
 	}
 
-	updateSQL := `UPDATE scheduler SET date = ? WHERE id = ?`
-	_, err = db.Exec(updateSQL, nextDate, task.ID)
-	if err != nil {
-		http.Error(w, `{"error":"Failed to update task"}`, http.StatusInternalServerError)
-		return
+	if next.IsZero() {
+		// Разовая задача или последняя дата серии: задача удаляется
+		if err := s.store.DeleteTask(task.ID); err != nil {
+			http.Error(w, `{"error":"Failed to delete task"}`, http.StatusInternalServerError)
+			return
+		}
+	} else {
+		setTaskMoment(&task, next)
+		if err := s.store.RescheduleTask(task.ID, task.Date, task.Time, completed); err != nil {
+			http.Error(w, `{"error":"Failed to update task"}`, http.StatusInternalServerError)
+			return
+		}
 	}
 
 	// Отправляем пустой JSON в случае успешного обновления
-	w.WriteHeader(http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(`{}`))
 
 }
 
-func DeleteTaskByID(w http.ResponseWriter, r *http.Request) error {
+func (s *Server) DeleteTaskByID(w http.ResponseWriter, r *http.Request) {
 	id := r.URL.Query().Get("id")
 	if id == "" {
-		return fmt.Errorf("task ID is required")
-	}
-
-	// Подключаемся к базе данных
-	db, err := sql.Open("sqlite3", "../scheduler.db")
-	if err != nil {
-		return fmt.Errorf("failed to connect to database: %v", err)
+		http.Error(w, `{"error":"ID is required"}`, http.StatusBadRequest)
+		return
 	}
-	defer db.Close()
 
 	// Выполняем удаление задачи
-	_, err = db.Exec("DELETE FROM scheduler WHERE id = ?", id)
-	if err != nil {
-		return fmt.Errorf("failed to delete task: %v", err)
+	err := s.store.DeleteTask(id)
+	if errors.Is(err, ErrTaskNotFound) {
+		http.Error(w, `{"error":"Task not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"error":"Failed to delete task"}`, http.StatusInternalServerError)
+		return
 	}
 
 	// Если задача успешно удалена, возвращаем пустой JSON {}
-	w.WriteHeader(http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(`{}`))
-	return nil
 }
 
 func respondWithError(w http.ResponseWriter, code int, message string) {
-	w.WriteHeader(code)
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
 	response := map[string]string{
 		"error": message,
 	}
@@ -357,8 +561,47 @@ func ApiNextDateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	*/
+	// Необязательные условия окончания серии
+	until := r.FormValue("until")
+	count := 0
+	if countStr := r.FormValue("count"); countStr != "" {
+		var err error
+		count, err = strconv.Atoi(countStr)
+		if err != nil {
+			http.Error(w, "Invalid count", http.StatusBadRequest)
+			return
+		}
+	}
 	// Вызываем функцию NextDate
-	nextDate, err := repeater.NextDate(nowStr, dateStr, repeat)
+	series := repeater.Series{
+		Date:     dateStr,
+		Repeat:   repeat,
+		Until:    until,
+		Count:    count,
+		Except:   r.FormValue("exdates"),
+		Shift:    r.FormValue("shift"),
+		Mode:     r.FormValue("mode"),
+		Overflow: r.FormValue("overflow"),
+	}
+	var nextDate string
+	var err error
+	if series.Mode == string(repeater.CompletionMode) {
+		// now — день выполнения, от которого отсчитывается следующая дата
+		done, err := time.Parse(layout, nowStr)
+		if err != nil {
+			http.Error(w, "Invalid 'now' date format", http.StatusBadRequest)
+			return
+		}
+		series.Location = time.UTC
+		next, err := series.Done(done)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		nextDate = next.Format(layout)
+	} else {
+		nextDate, err = series.Next(nowStr)
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -367,3 +610,157 @@ func ApiNextDateHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/plain")
 	w.Write([]byte(nextDate))
 }
+
+// Ограничение на количество дат в ответе /api/occurrences
+const (
+	defaultOccurrences = 10
+	maxOccurrences     = 1000
+)
+
+// ApiOccurrencesHandler возвращает ближайшие даты повторения задачи,
+// чтобы правило можно было проверить до сохранения.
+// Параметры: date, repeat, time, tz и overflow — как у задачи; now — дата,
+// после которой ищутся повторения (по умолчанию сегодня в поясе tz, а для
+// правил со временем — текущий момент); count — количество дат;
+// from и to — границы периода включительно.
+// Если у задачи есть время или правило задаёт его само ("h", "n", cron),
+// вместо дат возвращаются моменты в формате "20060102 15:04" в поясе tz.
+func ApiOccurrencesHandler(w http.ResponseWriter, r *http.Request) {
+	loc, err := taskLocation(Task{TZ: r.FormValue("tz")})
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid time zone")
+		return
+	}
+	series := repeater.Series{
+		Date:     r.FormValue("date"),
+		Repeat:   r.FormValue("repeat"),
+		Time:     r.FormValue("time"),
+		Location: loc,
+		Overflow: r.FormValue("overflow"),
+	}
+	rule, err := series.Rule()
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if rule.IsSubDaily() && series.Time == "" {
+		respondWithError(w, http.StatusBadRequest, "Time is required for hourly and minute repeat rules")
+		return
+	}
+	clock := rule.UsesClock()
+
+	// day возвращает начало дня из параметра: для правил со временем —
+	// момент в поясе tz, для остальных — дату
+	day := func(date string) (time.Time, error) {
+		if clock {
+			return repeater.At(date, "", loc)
+		}
+		return time.Parse(layout, date)
+	}
+	start, err := day(series.Date)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid date format")
+		return
+	}
+	if clock {
+		start, err = repeater.At(series.Date, series.Time, loc)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid time format")
+			return
+		}
+	}
+
+	// По умолчанию повторения ищутся после сегодняшнего дня в поясе tz
+	// или, для правил со временем, после текущего момента
+	after, _ := time.Parse(layout, repeater.Today(loc))
+	if clock {
+		after = time.Now()
+	}
+	if nowStr := r.FormValue("now"); nowStr != "" {
+		now, err := day(nowStr)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid 'now' date format")
+			return
+		}
+		// Как и в /api/nextdate, нужны повторения после всего дня now
+		after = now.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	if fromStr := r.FormValue("from"); fromStr != "" {
+		from, err := day(fromStr)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid 'from' date format")
+			return
+		}
+		// Граница включительная, поэтому ищем повторения с начала дня from
+		after = from.Add(-time.Nanosecond)
+	}
+	var until time.Time
+	if toStr := r.FormValue("to"); toStr != "" {
+		until, err = day(toStr)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid 'to' date format")
+			return
+		}
+		// Подходят все повторения в течение дня to
+		until = until.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	count := defaultOccurrences
+	if !until.IsZero() {
+		count = maxOccurrences
+	}
+	if countStr := r.FormValue("count"); countStr != "" {
+		count, err = strconv.Atoi(countStr)
+		if err != nil || count < 1 || count > maxOccurrences {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("count must be between 1 and %d", maxOccurrences))
+			return
+		}
+	}
+
+	dates := []string{}
+	for _, d := range rule.From(start).Occurrences(after, until, count) {
+		switch {
+		case clock:
+			d = d.In(loc)
+		case series.Time != "":
+			d, err = repeater.At(d.Format(layout), series.Time, loc)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, "Invalid time format")
+				return
+			}
+		default:
+			dates = append(dates, d.Format(layout))
+			continue
+		}
+		dates = append(dates, d.Format(layout+" 15:04"))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{"dates": dates})
+}
+
+// ApiParseHandler распознаёт фразу вроде "every other tuesday" или "через 3 дня"
+// и возвращает дату задачи и правило повторения
+func ApiParseHandler(w http.ResponseWriter, r *http.Request) {
+	loc, err := taskLocation(Task{TZ: r.FormValue("tz")})
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid time zone")
+		return
+	}
+	now, _ := time.Parse(layout, repeater.Today(loc))
+	if nowStr := r.FormValue("now"); nowStr != "" {
+		now, err = time.Parse(layout, nowStr)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid 'now' date format")
+			return
+		}
+	}
+
+	result, err := natural.Parse(r.FormValue("text"), now)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+
+	"modernc.org/sqlite"
+)
+
+// Настройки подключения к SQLite. В режиме WAL чтение не блокирует запись,
+// а одновременные записи ждут друг друга до sqliteBusyTimeout вместо
+// мгновенной ошибки "database is locked".
+const (
+	sqliteBusyTimeout  = 5000 // миллисекунды
+	sqliteMaxOpenConns = 8
+)
+
+// LIKE и lower() в SQLite переводят в нижний регистр только буквы ASCII,
+// поэтому для поиска без учёта регистра регистрируется своя функция
+const sqliteLower = "unicode_lower"
+
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction(sqliteLower, 1, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		switch v := args[0].(type) {
+		case string:
+			return strings.ToLower(v), nil
+		case []byte:
+			return strings.ToLower(string(v)), nil
+		}
+		return args[0], nil
+	})
+}
+
+// OpenSQLite открывает базу SQLite в файле path. Таблицы должны быть
+// уже созданы пакетом createDatabase.
+func OpenSQLite(path string) (*SQLStore, error) {
+	// Прагмы выполняются для каждого нового подключения из пула,
+	// а _txlock=immediate сразу берёт блокировку записи в транзакциях,
+	// чтобы они не падали при повышении блокировки
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(%d)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)&_txlock=immediate",
+		path, sqliteBusyTimeout)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(sqliteMaxOpenConns)
+	db.SetMaxIdleConns(sqliteMaxOpenConns)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLStore{db: db, dialect: sqliteDialect}, nil
+}
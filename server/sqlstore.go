@@ -0,0 +1,140 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+)
+
+// SQLStore — хранилище задач в таблице scheduler базы SQLite или PostgreSQL.
+// Подключение открывается один раз при запуске и используется всеми запросами.
+type SQLStore struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+// Close закрывает подключение к базе
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLStore) AddTask(task Task) (string, error) {
+	insertSQL := `INSERT INTO scheduler (date, title, comment, repeat, until, max_count, exdates, shift, time, tz, mode, overflow, anchor) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	args := []any{task.Date, task.Title, task.Comment, task.Repeat, task.Until, task.MaxCount, task.Exdates, task.Shift, task.Time, task.TZ, task.Mode, task.Overflow, task.Anchor}
+	var id int64
+	if s.dialect.returning {
+		err := s.db.QueryRow(s.dialect.rebind(insertSQL+" RETURNING id"), args...).Scan(&id)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		result, err := s.db.Exec(insertSQL, args...)
+		if err != nil {
+			return "", err
+		}
+		id, err = result.LastInsertId()
+		if err != nil {
+			return "", err
+		}
+	}
+	return strconv.FormatInt(id, 10), nil
+}
+
+func (s *SQLStore) GetTask(id string) (Task, error) {
+	taskID, err := parseTaskID(id)
+	if err != nil {
+		return Task{}, err
+	}
+	task, err := scanTask(s.db.QueryRow(s.dialect.rebind("SELECT "+taskColumns+" FROM scheduler WHERE id = ?"), taskID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Task{}, ErrTaskNotFound
+	}
+	return task, err
+}
+
+func (s *SQLStore) UpdateTask(task Task) error {
+	taskID, err := parseTaskID(task.ID)
+	if err != nil {
+		return err
+	}
+	updateSQL := `UPDATE scheduler SET date = ?, title = ?, comment = ?, repeat = ?, until = ?, max_count = ?, exdates = ?, shift = ?, time = ?, tz = ?, mode = ?, overflow = ?, anchor = ? WHERE id = ?`
+	return checkAffected(s.db.Exec(s.dialect.rebind(updateSQL), task.Date, task.Title, task.Comment, task.Repeat, task.Until, task.MaxCount, task.Exdates, task.Shift, task.Time, task.TZ, task.Mode, task.Overflow, task.Anchor, taskID))
+}
+
+func (s *SQLStore) DeleteTask(id string) error {
+	taskID, err := parseTaskID(id)
+	if err != nil {
+		return err
+	}
+	return checkAffected(s.db.Exec(s.dialect.rebind("DELETE FROM scheduler WHERE id = ?"), taskID))
+}
+
+func (s *SQLStore) RescheduleTask(id, date, clock string, completed bool) error {
+	taskID, err := parseTaskID(id)
+	if err != nil {
+		return err
+	}
+	updateSQL := `UPDATE scheduler SET date = ?, time = ? WHERE id = ?`
+	if completed {
+		updateSQL = `UPDATE scheduler SET date = ?, time = ?, done_count = done_count + 1 WHERE id = ?`
+	}
+	return checkAffected(s.db.Exec(s.dialect.rebind(updateSQL), date, clock, taskID))
+}
+
+func (s *SQLStore) ListTasks(filter TaskFilter) (TaskList, error) {
+	var zones []string
+	if filter.Overdue != nil {
+		var err error
+		zones, err = s.zones()
+		if err != nil {
+			return TaskList{}, err
+		}
+	}
+	q, err := newTaskQuery(filter, s.dialect, zones)
+	if err != nil {
+		return TaskList{}, err
+	}
+	return q.list(s.db)
+}
+
+// zones возвращает часовые пояса, которые встречаются у задач
+func (s *SQLStore) zones() ([]string, error) {
+	rows, err := s.db.Query("SELECT DISTINCT tz FROM scheduler")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var zones []string
+	for rows.Next() {
+		var tz string
+		if err := rows.Scan(&tz); err != nil {
+			return nil, err
+		}
+		zones = append(zones, tz)
+	}
+	return zones, rows.Err()
+}
+
+// parseTaskID разбирает id задачи. Задачи с нечисловым id не существует.
+func parseTaskID(id string) (int64, error) {
+	taskID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return 0, ErrTaskNotFound
+	}
+	return taskID, nil
+}
+
+// checkAffected возвращает ErrTaskNotFound, если запрос не изменил ни одной строки
+func checkAffected(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrTaskNotFound
+	}
+	return nil
+}
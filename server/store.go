@@ -0,0 +1,166 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TaskStore — хранилище задач, с которым работают обработчики API.
+// Реализация для SQLite — SQLiteStore.
+type TaskStore interface {
+	// AddTask сохраняет новую задачу и возвращает её id
+	AddTask(task Task) (string, error)
+	// GetTask возвращает задачу по id или ErrTaskNotFound
+	GetTask(id string) (Task, error)
+	// UpdateTask сохраняет все поля задачи, кроме счётчика выполнений,
+	// или возвращает ErrTaskNotFound
+	UpdateTask(task Task) error
+	// DeleteTask удаляет задачу или возвращает ErrTaskNotFound
+	DeleteTask(id string) error
+	// RescheduleTask переносит задачу на дату date и время clock.
+	// При completed счётчик выполнений увеличивается на единицу.
+	RescheduleTask(id, date, clock string, completed bool) error
+	// ListTasks возвращает страницу списка задач по фильтру
+	ListTasks(filter TaskFilter) (TaskList, error)
+	// Close закрывает хранилище
+	Close() error
+}
+
+var (
+	// ErrTaskNotFound возвращается, если задачи с таким id нет
+	ErrTaskNotFound = errors.New("task not found")
+	// ErrInvalidCursor возвращается для курсора, который не подходит к фильтру
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// Количество задач на странице /api/tasks по умолчанию и максимальное
+const (
+	defaultTaskLimit = 50
+	maxTaskLimit     = 500
+)
+
+// TaskList — страница списка задач. NextCursor передаётся в параметре cursor,
+// чтобы получить следующую страницу; на последней странице он пустой.
+type TaskList struct {
+	Tasks      []Task `json:"tasks"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// TaskFilter — условия списка задач. Даты в формате 20060102,
+// пустые поля и nil не ограничивают список.
+type TaskFilter struct {
+	// Search — поиск по тексту или дате в формате 02.01.2006
+	Search string
+	// Contains — подстрока в заголовке или комментарии
+	Contains string
+	// From и To — границы дат задач включительно
+	From, To  string
+	Recurring *bool
+	// Overdue — задачи, дата которых (а если задано время — его момент)
+	// уже прошла к моменту Now в часовом поясе задачи, или, если false, остальные
+	Overdue *bool
+	Now     time.Time
+	// Sort — поле сортировки: "date", "title" или "id"; пустое — по дате,
+	// а при поиске по тексту — по релевантности
+	Sort string
+	Desc bool
+	// Limit — размер страницы, Cursor — курсор из предыдущей страницы
+	Limit  int
+	Cursor string
+}
+
+// Поля, по которым можно сортировать список параметром sort
+var sortFields = map[string]bool{"date": true, "title": true, "id": true}
+
+// parseTaskFilter разбирает параметры списка задач:
+//
+//	search    — поиск по тексту или дате 02.01.2006
+//	contains  — подстрока в заголовке или комментарии
+//	from, to  — даты задач включительно (20060102 или 02.01.2006)
+//	recurring — true для повторяющихся задач, false для разовых
+//	overdue   — true для задач, дата и время которых прошли в их часовом поясе,
+//	            false для остальных
+//	sort      — date, title или id, с минусом для обратного порядка: sort=-date
+//	limit, cursor — страница списка
+//
+// Вторым значением возвращается сообщение об ошибке в параметрах.
+func parseTaskFilter(r *http.Request) (TaskFilter, string) {
+	filter := TaskFilter{
+		Search:   strings.TrimSpace(r.FormValue("search")),
+		Contains: r.FormValue("contains"),
+		Now:      time.Now(),
+		Limit:    defaultTaskLimit,
+		Cursor:   r.FormValue("cursor"),
+	}
+	if limitStr := r.FormValue("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > maxTaskLimit {
+			return filter, fmt.Sprintf("limit must be between 1 and %d", maxTaskLimit)
+		}
+		filter.Limit = limit
+	}
+
+	for _, bound := range []struct {
+		param string
+		date  *string
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		value := r.FormValue(bound.param)
+		if value == "" {
+			continue
+		}
+		date, err := parseFilterDate(value)
+		if err != nil {
+			return filter, "Invalid '" + bound.param + "' date format"
+		}
+		*bound.date = date
+	}
+	for _, flag := range []struct {
+		param string
+		value **bool
+	}{{"recurring", &filter.Recurring}, {"overdue", &filter.Overdue}} {
+		value := r.FormValue(flag.param)
+		if value == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, "Invalid '" + flag.param + "' value"
+		}
+		*flag.value = &b
+	}
+
+	if value := r.FormValue("sort"); value != "" {
+		filter.Sort, filter.Desc = strings.CutPrefix(value, "-")
+		if !sortFields[filter.Sort] {
+			return filter, "Invalid sort field"
+		}
+	}
+	return filter, ""
+}
+
+// parseFilterDate разбирает дату в формате 20060102 или 02.01.2006
+func parseFilterDate(value string) (string, error) {
+	date, err := time.Parse(layout, value)
+	if err != nil {
+		date, err = time.Parse(searchDateLayout, value)
+	}
+	if err != nil {
+		return "", err
+	}
+	return date.Format(layout), nil
+}
+
+// zoneNow возвращает дату и время суток момента now в часовом поясе tz.
+// Для неизвестного пояса берётся пояс сервера.
+func zoneNow(now time.Time, tz string) (date, clock string) {
+	loc, err := taskLocation(Task{TZ: tz})
+	if err != nil {
+		loc = time.Local
+	}
+	now = now.In(loc)
+	return now.Format(layout), now.Format("15:04")
+}
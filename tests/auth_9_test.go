@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MirekKrassilnikov/go_final_project/server"
+	"github.com/stretchr/testify/assert"
+)
+
+const authPassword = "secret"
+
+// signToken подписывает токен JWT HS256 с заданными iat и exp
+func signToken(password string, issued, expires time.Time) string {
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." +
+		enc.EncodeToString([]byte(fmt.Sprintf(`{"iat":%d,"exp":%d}`, issued.Unix(), expires.Unix())))
+	mac := hmac.New(sha256.New, []byte(password))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + enc.EncodeToString(mac.Sum(nil))
+}
+
+// authRequest выполняет запрос к серверу с паролем и возвращает код ответа и JSON
+func authRequest(t *testing.T, req *http.Request) (*http.Response, map[string]string) {
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	var m map[string]string
+	assert.NoError(t, json.Unmarshal(body, &m), string(body))
+	return resp, m
+}
+
+func TestSignin(t *testing.T) {
+	srv := httptest.NewServer(server.NewHandler(server.NewMemoryStore(), "../web", authPassword))
+	defer srv.Close()
+
+	signin := func(password string) (*http.Response, map[string]string) {
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/signin",
+			strings.NewReader(`{"password":"`+password+`"}`))
+		assert.NoError(t, err)
+		return authRequest(t, req)
+	}
+
+	resp, m := signin("wrong")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.NotEmpty(t, m["error"])
+	assert.Empty(t, resp.Cookies())
+
+	resp, m = signin(authPassword)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEmpty(t, m["token"])
+	var cookie *http.Cookie
+	for _, c := range resp.Cookies() {
+		if c.Name == "token" {
+			cookie = c
+		}
+	}
+	if assert.NotNil(t, cookie) {
+		assert.Equal(t, m["token"], cookie.Value)
+		assert.True(t, cookie.HttpOnly)
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	srv := httptest.NewServer(server.NewHandler(server.NewMemoryStore(), "../web", authPassword))
+	defer srv.Close()
+
+	valid, err := server.NewAuth(authPassword).NewToken()
+	assert.NoError(t, err)
+	now := time.Now()
+	tampered := valid[:len(valid)-1] + "A"
+	if tampered == valid {
+		tampered = valid[:len(valid)-1] + "B"
+	}
+
+	tbl := []struct {
+		name  string
+		token string
+		code  int
+	}{
+		{"без токена", "", http.StatusUnauthorized},
+		{"изменённая подпись", tampered, http.StatusUnauthorized},
+		{"чужой пароль", signToken("other", now, now.Add(time.Hour)), http.StatusUnauthorized},
+		{"истёкший", signToken(authPassword, now.Add(-9*time.Hour), now.Add(-time.Hour)), http.StatusUnauthorized},
+		{"не JWT", "garbage", http.StatusUnauthorized},
+		{"действующий", valid, http.StatusOK},
+	}
+	for _, v := range tbl {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/tasks", nil)
+		assert.NoError(t, err)
+		if v.token != "" {
+			req.AddCookie(&http.Cookie{Name: "token", Value: v.token})
+		}
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.NoError(t, err)
+		assert.Equal(t, v.code, resp.StatusCode, v.name)
+		if v.code == http.StatusOK {
+			continue
+		}
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"), v.name)
+		var m map[string]string
+		assert.NoError(t, json.Unmarshal(body, &m), v.name)
+		assert.NotEmpty(t, m["error"], v.name)
+	}
+
+	// Правило повторения можно проверить и без входа
+	resp, err := http.Get(srv.URL + "/api/nextdate?now=20240126&date=20240126&repeat=d+1")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MirekKrassilnikov/go_final_project/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clearConfigEnv убирает переменные окружения с настройками на время теста
+func clearConfigEnv(t *testing.T) {
+	for _, name := range []string{"TODO_CONFIG", "TODO_PORT", "TODO_DBFILE", "TODO_DSN", "TODO_WEBDIR", "TODO_HOLIDAYS", "TODO_PASSWORD"} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestConfigLoad(t *testing.T) {
+	clearConfigEnv(t)
+
+	// Без флагов, переменных и файла — значения по умолчанию
+	cfg, rest, err := config.Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, config.Default(), cfg)
+	assert.Empty(t, rest)
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"port": 8001,
+		"dbfile": "file.db",
+		"webdir": "file-web",
+		"holidays": "file-holidays.txt",
+		"password": "file-secret"
+	}`), 0o644))
+
+	// Файл заменяет значения по умолчанию
+	cfg, _, err = config.Load([]string{"-config", path})
+	require.NoError(t, err)
+	assert.Equal(t, config.Config{
+		Port:     8001,
+		DBFile:   "file.db",
+		WebDir:   "file-web",
+		Holidays: "file-holidays.txt",
+		Password: "file-secret",
+	}, cfg)
+
+	// Переменные окружения важнее файла, путь к которому тоже задан переменной
+	t.Setenv("TODO_CONFIG", path)
+	t.Setenv("TODO_PORT", "8002")
+	t.Setenv("TODO_DBFILE", "env.db")
+	t.Setenv("TODO_PASSWORD", "env-secret")
+	cfg, _, err = config.Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, config.Config{
+		Port:     8002,
+		DBFile:   "env.db",
+		WebDir:   "file-web",
+		Holidays: "file-holidays.txt",
+		Password: "env-secret",
+	}, cfg)
+
+	// Флаги важнее всего, аргументы после них возвращаются отдельно
+	cfg, rest, err = config.Load([]string{"-port", "8003", "-webdir", "flag-web", "migrate", "status"})
+	require.NoError(t, err)
+	assert.Equal(t, config.Config{
+		Port:     8003,
+		DBFile:   "env.db",
+		WebDir:   "flag-web",
+		Holidays: "file-holidays.txt",
+		Password: "env-secret",
+	}, cfg)
+	assert.Equal(t, []string{"migrate", "status"}, rest)
+}
+
+func TestConfigLoadErrors(t *testing.T) {
+	clearConfigEnv(t)
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"prot": 8001}`), 0o644))
+
+	for _, args := range [][]string{
+		{"-config", path},
+		{"-config", filepath.Join(t.TempDir(), "missing.json")},
+		{"-port", "70000"},
+		{"-dsn", "mysql://localhost/db"},
+		{"-unknown"},
+	} {
+		_, _, err := config.Load(args)
+		assert.Error(t, err, args)
+	}
+
+	t.Setenv("TODO_PORT", "port")
+	_, _, err := config.Load(nil)
+	assert.Error(t, err)
+}
@@ -2,20 +2,33 @@ package tests
 
 import (
 	"os"
+	"strconv"
 	"testing"
 	"time"
 
+	"github.com/MirekKrassilnikov/go_final_project/server"
+	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
 )
 
 type Task struct {
-	ID      int64  `db:"id"`
-	Date    string `db:"date"`
-	Title   string `db:"title"`
-	Comment string `db:"comment"`
-	Repeat  string `db:"repeat"`
+	ID        int64  `db:"id"`
+	Date      string `db:"date"`
+	Title     string `db:"title"`
+	Comment   string `db:"comment"`
+	Repeat    string `db:"repeat"`
+	Until     string `db:"until"`
+	MaxCount  int64  `db:"max_count"`
+	DoneCount int64  `db:"done_count"`
+	Exdates   string `db:"exdates"`
+	Shift     string `db:"shift"`
+	Time      string `db:"time"`
+	TZ        string `db:"tz"`
+	Mode      string `db:"mode"`
+	Overflow  string `db:"overflow"`
+	Anchor    string `db:"anchor"`
 }
 
 func count(db *sqlx.DB) (int, error) {
@@ -23,43 +36,111 @@ func count(db *sqlx.DB) (int, error) {
 	return count, db.Get(&count, `SELECT count(id) FROM scheduler`)
 }
 
-func openDB(t *testing.T) *sqlx.DB {
+// testDB — база, в которой сервер хранит задачи. У сервера, запущенного
+// в процессе тестов, это его хранилище в памяти.
+type testDB struct {
+	*sqlx.DB
+	memory *server.MemoryStore
+}
+
+func (db *testDB) Close() error {
+	if db.memory != nil {
+		return nil
+	}
+	return db.DB.Close()
+}
+
+// task возвращает задачу из базы по id
+func (db *testDB) task(id string) (Task, error) {
+	var task Task
+	if db.memory == nil {
+		err := db.Get(&task, db.Rebind(`SELECT * FROM scheduler WHERE id=?`), id)
+		return task, err
+	}
+
+	stored, err := db.memory.GetTask(id)
+	if err != nil {
+		return task, err
+	}
+	task.ID, err = strconv.ParseInt(stored.ID, 10, 64)
+	task.Date, task.Title, task.Comment, task.Repeat = stored.Date, stored.Title, stored.Comment, stored.Repeat
+	task.Until, task.MaxCount, task.DoneCount = stored.Until, int64(stored.MaxCount), int64(stored.DoneCount)
+	task.Exdates, task.Shift, task.Time, task.TZ = stored.Exdates, stored.Shift, stored.Time, stored.TZ
+	task.Mode, task.Overflow, task.Anchor = stored.Mode, stored.Overflow, stored.Anchor
+	return task, err
+}
+
+// clear удаляет из базы все задачи
+func (db *testDB) clear() error {
+	if db.memory == nil {
+		_, err := db.Exec("DELETE FROM scheduler")
+		return err
+	}
+
+	for {
+		list, err := db.memory.ListTasks(server.TaskFilter{Limit: 100})
+		if err != nil || len(list.Tasks) == 0 {
+			return err
+		}
+		for _, task := range list.Tasks {
+			if err := db.memory.DeleteTask(task.ID); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func openDB(t *testing.T) *testDB {
+	if memoryStore != nil {
+		return &testDB{memory: memoryStore}
+	}
+
+	// Сервер, запущенный с базой PostgreSQL
+	if dsn := os.Getenv("TODO_DSN"); len(dsn) > 0 {
+		db, err := sqlx.Connect("pgx", dsn)
+		assert.NoError(t, err)
+		return &testDB{DB: db}
+	}
+
 	dbfile := DBFile
 	envFile := os.Getenv("TODO_DBFILE")
 	if len(envFile) > 0 {
 		dbfile = envFile
 	}
-	db, err := sqlx.Connect("sqlite3", dbfile)
+	// Драйвер с FTS5: поисковый индекс обновляют триггеры базы
+	db, err := sqlx.Connect("sqlite", dbfile)
 	assert.NoError(t, err)
-	return db
+	return &testDB{DB: db}
 }
 
 func TestDB(t *testing.T) {
 	db := openDB(t)
 	defer db.Close()
+	if db.memory != nil {
+		t.Skip("сервер запущен в процессе тестов без базы данных")
+	}
 
-	before, err := count(db)
+	before, err := count(db.DB)
 	assert.NoError(t, err)
 
 	today := time.Now().Format(`20060102`)
 
-	res, err := db.Exec(`INSERT INTO scheduler (date, title, comment, repeat) 
-	VALUES (?, 'Todo', 'Комментарий', '')`, today)
+	var id int64
+	err = db.Get(&id, db.Rebind(`INSERT INTO scheduler (date, title, comment, repeat) 
+	VALUES (?, 'Todo', 'Комментарий', '') RETURNING id`), today)
 	assert.NoError(t, err)
 
-	id, err := res.LastInsertId()
-
 	var task Task
-	err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+	err = db.Get(&task, db.Rebind(`SELECT * FROM scheduler WHERE id=?`), id)
 	assert.NoError(t, err)
 	assert.Equal(t, id, task.ID)
 	assert.Equal(t, `Todo`, task.Title)
 	assert.Equal(t, `Комментарий`, task.Comment)
 
-	_, err = db.Exec(`DELETE FROM scheduler WHERE id = ?`, id)
+	_, err = db.Exec(db.Rebind(`DELETE FROM scheduler WHERE id = ?`), id)
 	assert.NoError(t, err)
 
-	after, err := count(db)
+	after, err := count(db.DB)
 	assert.NoError(t, err)
 
 	assert.Equal(t, before, after)
@@ -0,0 +1,49 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/MirekKrassilnikov/go_final_project/holidays"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHolidaysLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "holidays.txt")
+	require.NoError(t, os.WriteFile(path, []byte(`# Праздники
+20240108 # последний день каникул
+
+0308
+`), 0o644))
+
+	cal, err := holidays.Load(path)
+	require.NoError(t, err)
+	tbl := []struct {
+		date    string
+		workday bool
+	}{
+		{"20240108", false},
+		{"20250108", true},
+		{"20240308", false},
+		{"20250307", true},
+		{"20250308", false},
+		{"20240127", false},
+		{"20240129", true},
+	}
+	for _, v := range tbl {
+		day, err := time.Parse("20060102", v.date)
+		require.NoError(t, err)
+		assert.Equal(t, v.workday, cal.IsWorkday(day), v.date)
+	}
+
+	// Ошибка указывает на строку файла
+	require.NoError(t, os.WriteFile(path, []byte("0101\n20240230\n"), 0o644))
+	_, err = holidays.Load(path)
+	assert.ErrorContains(t, err, path+":2:")
+
+	_, err = holidays.Load(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err)
+}
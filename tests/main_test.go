@@ -0,0 +1,43 @@
+package tests
+
+import (
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/MirekKrassilnikov/go_final_project/server"
+)
+
+// Адрес и хранилище сервера, запущенного в процессе тестов
+var (
+	serverURL   string
+	memoryStore *server.MemoryStore
+)
+
+func TestMain(m *testing.M) {
+	inProcess := InProcess
+	if env := os.Getenv("TODO_INPROCESS"); len(env) > 0 {
+		inProcess, _ = strconv.ParseBool(env)
+	}
+	if !inProcess {
+		os.Exit(m.Run())
+	}
+
+	// С паролем запросы к задачам идут с токеном, выданным для него
+	password := os.Getenv("TODO_PASSWORD")
+	if len(password) > 0 && len(Token) == 0 {
+		token, err := server.NewAuth(password).NewToken()
+		if err != nil {
+			panic(err)
+		}
+		Token = token
+	}
+
+	memoryStore = server.NewMemoryStore()
+	srv := httptest.NewServer(server.NewHandler(memoryStore, "../web", password))
+	serverURL = srv.URL
+	code := m.Run()
+	srv.Close()
+	os.Exit(code)
+}
@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/MirekKrassilnikov/go_final_project/createDatabase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openTempDB открывает пустую базу SQLite во временном файле
+func openTempDB(t *testing.T) *createDatabase.Database {
+	db, err := createDatabase.Open(filepath.Join(t.TempDir(), "scheduler.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// schemaVersion возвращает версию схемы, как команда migrate status
+func schemaVersion(t *testing.T, db *createDatabase.Database) int {
+	version, err := createDatabase.Version(db)
+	require.NoError(t, err)
+	return version
+}
+
+// hasColumn сообщает, есть ли колонка в таблице scheduler
+func hasColumn(t *testing.T, db *createDatabase.Database, name string) bool {
+	var n int
+	err := db.QueryRow(`SELECT count(*) FROM pragma_table_info('scheduler') WHERE name = ?`, name).Scan(&n)
+	require.NoError(t, err)
+	return n > 0
+}
+
+// hasTable сообщает, есть ли в базе таблица name
+func hasTable(t *testing.T, db *createDatabase.Database, name string) bool {
+	var n int
+	err := db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE name = ?`, name).Scan(&n)
+	require.NoError(t, err)
+	return n > 0
+}
+
+// searchIDs возвращает id задач, найденных в поисковом индексе
+func searchIDs(t *testing.T, db *createDatabase.Database, text string) []int64 {
+	rows, err := db.Query(`SELECT rowid FROM scheduler_fts WHERE scheduler_fts MATCH ? ORDER BY rowid`, `"`+text+`"`)
+	require.NoError(t, err)
+	defer rows.Close()
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		require.NoError(t, rows.Scan(&id))
+		ids = append(ids, id)
+	}
+	require.NoError(t, rows.Err())
+	return ids
+}
+
+func TestMigrate(t *testing.T) {
+	db := openTempDB(t)
+	latest := createDatabase.Latest()
+	assert.Equal(t, 0, schemaVersion(t, db))
+
+	// up
+	require.NoError(t, createDatabase.Migrate(db))
+	assert.Equal(t, latest, schemaVersion(t, db))
+	assert.True(t, hasColumn(t, db, "anchor"))
+	// Повторный запуск ничего не меняет
+	require.NoError(t, createDatabase.Migrate(db))
+	assert.Equal(t, latest, schemaVersion(t, db))
+
+	_, err := db.Exec(`INSERT INTO scheduler (date, title, comment, repeat) VALUES ('20240126', 'Купить молоко', '', '')`)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1}, searchIDs(t, db, "молоко"))
+
+	// down на один шаг
+	require.NoError(t, createDatabase.MigrateTo(db, latest-1))
+	assert.Equal(t, latest-1, schemaVersion(t, db))
+	assert.False(t, hasColumn(t, db, "anchor"))
+	assert.Equal(t, []int64{1}, searchIDs(t, db, "молоко"))
+
+	// to: к схеме до колонок повторения и обратно, задачи сохраняются
+	require.NoError(t, createDatabase.MigrateTo(db, 1))
+	assert.Equal(t, 1, schemaVersion(t, db))
+	assert.False(t, hasColumn(t, db, "until"))
+	assert.False(t, hasTable(t, db, "scheduler_fts"))
+	require.NoError(t, createDatabase.MigrateTo(db, latest))
+	assert.Equal(t, latest, schemaVersion(t, db))
+	assert.True(t, hasColumn(t, db, "until"))
+	assert.Equal(t, []int64{1}, searchIDs(t, db, "молоко"))
+
+	// Поисковый индекс следует за изменениями задач
+	_, err = db.Exec(`UPDATE scheduler SET title = 'Купить хлеб' WHERE id = 1`)
+	require.NoError(t, err)
+	assert.Empty(t, searchIDs(t, db, "молоко"))
+	assert.Equal(t, []int64{1}, searchIDs(t, db, "хлеб"))
+	_, err = db.Exec(`DELETE FROM scheduler WHERE id = 1`)
+	require.NoError(t, err)
+	assert.Empty(t, searchIDs(t, db, "хлеб"))
+
+	// Неизвестные версии
+	assert.Error(t, createDatabase.MigrateTo(db, latest+1))
+	assert.Error(t, createDatabase.MigrateTo(db, -1))
+
+	// to 0 удаляет все таблицы
+	require.NoError(t, createDatabase.MigrateTo(db, 0))
+	assert.Equal(t, 0, schemaVersion(t, db))
+	assert.False(t, hasTable(t, db, "scheduler"))
+}
+
+func TestMigrateLegacy(t *testing.T) {
+	db := openTempDB(t)
+
+	// База, созданная до появления миграций: таблица из первой версии
+	// и часть колонок повторения, которые добавлял сервер при запуске
+	_, err := db.Exec(`
+	CREATE TABLE scheduler (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		date TEXT,
+		title TEXT,
+		comment TEXT,
+		repeat TEXT
+	);
+	CREATE INDEX idx_date ON scheduler(date);
+	ALTER TABLE scheduler ADD COLUMN until TEXT NOT NULL DEFAULT '';
+	ALTER TABLE scheduler ADD COLUMN max_count INTEGER NOT NULL DEFAULT 0;
+	INSERT INTO scheduler (date, title, comment, repeat, until) VALUES
+		('20240126', 'Полить цветы', 'на балконе', 'd 3', '20241231'),
+		('20240127', 'Позвонить маме', '', '', '');
+	`)
+	require.NoError(t, err)
+	assert.Equal(t, 0, schemaVersion(t, db))
+
+	require.NoError(t, createDatabase.Migrate(db))
+	assert.Equal(t, createDatabase.Latest(), schemaVersion(t, db))
+	for _, column := range []string{"until", "max_count", "done_count", "overflow", "anchor"} {
+		assert.True(t, hasColumn(t, db, column), column)
+	}
+
+	// Задачи и их поля сохранились и попали в поисковый индекс
+	var task Task
+	err = db.QueryRow(`SELECT id, title, comment, repeat, until, done_count, anchor FROM scheduler WHERE id = 1`).
+		Scan(&task.ID, &task.Title, &task.Comment, &task.Repeat, &task.Until, &task.DoneCount, &task.Anchor)
+	require.NoError(t, err)
+	assert.Equal(t, Task{ID: 1, Title: "Полить цветы", Comment: "на балконе", Repeat: "d 3", Until: "20241231"}, task)
+	assert.Equal(t, []int64{1}, searchIDs(t, db, "балкон"))
+	assert.Equal(t, []int64{2}, searchIDs(t, db, "маме"))
+
+	// Откат до первой версии оставляет таблицу задач без новых колонок
+	require.NoError(t, createDatabase.MigrateTo(db, 1))
+	assert.False(t, hasColumn(t, db, "until"))
+	var count int
+	require.NoError(t, db.QueryRow(`SELECT count(*) FROM scheduler`).Scan(&count))
+	assert.Equal(t, 2, count)
+}
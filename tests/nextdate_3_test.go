@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/url"
 	"strings"
@@ -77,3 +78,131 @@ func TestNextDate(t *testing.T) {
 	}
 	check()
 }
+
+// seriesDate — строка таблицы для /api/nextdate с условиями серии:
+// params — дополнительные параметры запроса, например "shift=next"
+type seriesDate struct {
+	now    string
+	date   string
+	repeat string
+	params string
+	want   string
+}
+
+func TestNextDateSeries(t *testing.T) {
+	tbl := []seriesDate{
+		// RRULE
+		{"20240126", "20240101", "FREQ=WEEKLY;BYDAY=MO,WE", "", "20240129"},
+		{"20240126", "20240101", "FREQ=MONTHLY;BYDAY=-1FR", "", "20240223"},
+		{"20240126", "20240101", "FREQ=DAILY;INTERVAL=10", "", "20240131"},
+		{"20240126", "20240101", "FREQ=DAILY;COUNT=3", "", ""},
+		{"20240126", "20240101", "FREQ=DAILY;COUNT=30", "", "20240127"},
+		{"20240126", "20240101", "FREQ=WEEKLY;UNTIL=20240128", "", ""},
+		{"20240126", "20240101", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", "", ""},
+		{"20240126", "20200229", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29", "", "20240229"},
+		{"20240301", "20240229", "FREQ=YEARLY", "", "20280229"},
+		{"20240126", "20240101", "FREQ=DAILY;COUNT=10001", "", ""},
+		// cron
+		{"20240126", "20240101", "0 9 * * 1", "", "20240129"},
+		{"20240126", "20240101", "@monthly", "", "20240201"},
+		{"20240126", "20240101", "0 9 * *", "", ""},
+		{"20240126", "20240101", "0 0 31 2 *", "", ""},
+		{"20240126", "20240101", "0 0 30 2 *", "", ""},
+		{"20240126", "20240101", "0 0 31 4,6,9,11 *", "", ""},
+		{"20240126", "20240101", "0 0 29 2 *", "", "20240229"},
+		{"20240126", "20240101", "0 0 31 2 1", "", "20240205"},
+		// Рабочие дни и перенос с выходных
+		{"20240126", "20240126", "b 1", "", "20240129"},
+		{"20240126", "20240122", "b 5", "", "20240129"},
+		{"20240126", "20240106", "d 7", "shift=next", "20240129"},
+		{"20240126", "20240106", "d 7", "shift=prev", "20240202"},
+		{"20240126", "20240106", "d 7", "shift=later", ""},
+		// Правила чаще раза в день: ближайший момент после дня now
+		{"20240126", "20240126", "h 4", "", "20240127"},
+		{"20240126", "20240126", "n 30 09:00-17:00", "", "20240127"},
+		{"20240126", "20240126", "n 30 17:00-09:00", "", ""},
+		{"20240126", "17000101", "h 1", "", "20240127"},
+		// Даты, которых нет в месяце
+		{"20240126", "20240131", "m 31", "", "20240331"},
+		{"20240126", "20240131", "m 31", "overflow=clamp", "20240229"},
+		{"20240126", "20240131", "m 31", "overflow=forward", "20240301"},
+		{"20240126", "20240131", "m 30 2", "", ""},
+		{"20250101", "20240229", "y", "", "20250301"},
+		{"20250101", "20240229", "y", "overflow=clamp", "20250228"},
+		{"20250101", "20240229", "y", "overflow=forward", "20250301"},
+		{"20250101", "20240229", "y", "overflow=skip", "20280229"},
+		{"20240301", "20240229", "m 29 2", "", "20280229"},
+		// Режим от выполнения: now — день выполнения
+		{"20240126", "20240101", "d 3", "", "20240128"},
+		{"20240126", "20240101", "d 3", "mode=completion", "20240129"},
+		{"20240126", "20230615", "y", "mode=completion", "20250126"},
+		// Условия окончания
+		{"20240126", "20240101", "d 13", "until=20240127", "20240127"},
+		{"20240126", "20240101", "d 13", "until=20240126", ""},
+		{"20240126", "20240101", "d 10", "count=3", ""},
+		{"20240126", "20240101", "d 10", "count=4", "20240131"},
+		{"20240126", "19000101", "n 1", "count=2000000000", ""},
+		// Исключённые даты
+		{"20240126", "20240101", "w 1", "exdates=20240129", "20240205"},
+	}
+	for _, v := range tbl {
+		params, err := url.ParseQuery(v.params)
+		assert.NoError(t, err)
+		params.Set("now", v.now)
+		params.Set("date", v.date)
+		params.Set("repeat", v.repeat)
+		get, err := getBody("api/nextdate?" + params.Encode())
+		assert.NoError(t, err)
+		next := strings.TrimSpace(string(get))
+		_, err = time.Parse("20060102", next)
+		if err != nil && len(v.want) == 0 {
+			continue
+		}
+		assert.Equal(t, v.want, next, `{%q, %q, %q, %q, %q}`,
+			v.now, v.date, v.repeat, v.params, v.want)
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	tbl := []struct {
+		params string
+		repeat string
+		want   []string
+	}{
+		{"date=20240126&from=20240126&count=4&time=09:00&tz=UTC", "n 30 09:00-10:00",
+			[]string{"20240126 09:30", "20240126 10:00", "20240127 09:00", "20240127 09:30"}},
+		{"date=20240126&from=20240126&count=3&time=08:00&tz=UTC", "h 4 08:00-17:00",
+			[]string{"20240126 12:00", "20240126 16:00", "20240127 08:00"}},
+		{"date=20240126&from=20240126&count=3&time=21:00&tz=UTC", "h 6",
+			[]string{"20240127 03:00", "20240127 09:00", "20240127 15:00"}},
+		{"date=20240126&now=20240126&count=2&tz=Europe/Moscow", "30 9 * * 1-5",
+			[]string{"20240129 09:30", "20240130 09:30"}},
+		{"date=20240126&now=20240126&count=2&time=08:00&tz=UTC", "d 2",
+			[]string{"20240128 08:00", "20240130 08:00"}},
+		{"date=17000101&from=20240126&count=2&time=00:30&tz=UTC", "h 1",
+			[]string{"20240126 00:30", "20240126 01:30"}},
+		{"date=20240131&now=20240126&count=3", "m 31",
+			[]string{"20240331", "20240531", "20240731"}},
+		{"date=20240131&now=20240126&count=3&overflow=clamp", "m 31",
+			[]string{"20240229", "20240331", "20240430"}},
+		{"date=20240126&from=20240126&to=20240202", "w 1,5",
+			[]string{"20240129", "20240202"}},
+	}
+	for _, v := range tbl {
+		params, err := url.ParseQuery(v.params)
+		assert.NoError(t, err)
+		params.Set("repeat", v.repeat)
+		body, err := getBody("api/occurrences?" + params.Encode())
+		assert.NoError(t, err)
+		var resp struct {
+			Dates []string `json:"dates"`
+		}
+		assert.NoError(t, json.Unmarshal(body, &resp), string(body))
+		assert.Equal(t, v.want, resp.Dates, "%s repeat=%s", v.params, v.repeat)
+	}
+
+	// Для правил "h" и "n" нужно время задачи
+	body, err := getBody("api/occurrences?date=20240126&repeat=h+4")
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "error")
+}
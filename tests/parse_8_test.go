@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type parsedPhrase struct {
+	text   string
+	date   string
+	repeat string
+}
+
+func TestParse(t *testing.T) {
+	// 26.01.2024 — пятница
+	tbl := []parsedPhrase{
+		{"tomorrow", "20240127", ""},
+		{"next friday", "20240202", ""},
+		{"in 3 days", "20240129", ""},
+		{"28.01.2024", "20240128", ""},
+		{"every day", "20240126", "d 1"},
+		{"every 2 weeks", "20240126", "d 14"},
+		{"every other Tuesday", "20240130", "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU"},
+		{"every Monday and Friday", "20240126", "w 1,5"},
+		{"every weekday", "20240126", "w 1,2,3,4,5"},
+		{"last day of every month", "20240131", "m -1"},
+		{"15th of every month", "20240215", "m 15"},
+		{"завтра", "20240127", ""},
+		{"послезавтра", "20240128", ""},
+		{"в пятницу", "20240202", ""},
+		{"через 3 дня", "20240129", ""},
+		{"через 2 недели", "20240209", ""},
+		{"через день", "20240126", "d 2"},
+		{"Каждую неделю", "20240126", "d 7"},
+		{"по понедельникам и средам", "20240129", "w 1,3"},
+		{"каждый второй вторник", "20240130", "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU"},
+		{"в последний день месяца", "20240131", "m -1"},
+		{"15 числа каждого месяца", "20240215", "m 15"},
+		{"", "", ""},
+		{"every blue moon", "", ""},
+		{"когда-нибудь", "", ""},
+	}
+	for _, v := range tbl {
+		body, err := getBody("api/parse?now=20240126&text=" + url.QueryEscape(v.text))
+		assert.NoError(t, err)
+		var m map[string]string
+		assert.NoError(t, json.Unmarshal(body, &m), string(body))
+		if v.date == "" {
+			assert.NotEmpty(t, m["error"], "Ожидается ошибка для %q", v.text)
+			continue
+		}
+		assert.Empty(t, m["error"], v.text)
+		assert.Equal(t, v.date, m["date"], v.text)
+		assert.Equal(t, v.repeat, m["repeat"], v.text)
+	}
+}
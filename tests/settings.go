@@ -2,6 +2,10 @@ package tests
 
 var Port = 7540
 var DBFile = "../scheduler.db"
-var FullNextDate = false
-var Search = false
+var FullNextDate = true
+var Search = true
 var Token = ``
+
+// InProcess запускает сервер в процессе тестов с хранилищем в памяти
+// вместо обращения к серверу на порту Port
+var InProcess = false
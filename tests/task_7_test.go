@@ -2,6 +2,7 @@ package tests
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"testing"
 	"time"
@@ -45,13 +46,139 @@ func TestDone(t *testing.T) {
 		assert.Empty(t, ret)
 
 		var task Task
-		err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+		task, err = db.task(id)
 		assert.NoError(t, err)
 		now = now.AddDate(0, 0, 3)
 		assert.Equal(t, task.Date, now.Format(`20060102`))
 	}
 }
 
+func TestDoneSeries(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	// COUNT считается от первой даты серии, а не от текущей даты задачи
+	now := time.Now()
+	id := addTask(t, task{
+		date:   now.Format(`20060102`),
+		title:  "Три дня подряд",
+		repeat: "FREQ=DAILY;COUNT=3",
+	})
+	for i := 0; i < 2; i++ {
+		ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+
+		task, err := db.task(id)
+		assert.NoError(t, err)
+		now = now.AddDate(0, 0, 1)
+		assert.Equal(t, now.Format(`20060102`), task.Date)
+	}
+	ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+
+	// 29 февраля с переносом на 28-е возвращается в високосный год
+	ret, err = postJSON("api/task", map[string]any{
+		"date":     "20280229",
+		"title":    "Годовщина",
+		"repeat":   "y",
+		"overflow": "clamp",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id = fmt.Sprint(ret["id"])
+	for _, want := range []string{"20290228", "20300228", "20310228", "20320229"} {
+		ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+
+		task, err := db.task(id)
+		assert.NoError(t, err)
+		assert.Equal(t, want, task.Date)
+	}
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	// Ограничение не может быть меньше уже выполненных дат
+	id = addTask(t, task{
+		date:   now.Format(`20060102`),
+		title:  "Пять раз",
+		repeat: "d 1",
+	})
+	ret, err = postJSON("api/task", map[string]any{
+		"id":        id,
+		"date":      now.Format(`20060102`),
+		"title":     "Пять раз",
+		"repeat":    "d 1",
+		"max_count": 5,
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	for i := 0; i < 2; i++ {
+		ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+	stored, err := db.task(id)
+	assert.NoError(t, err)
+	for _, maxCount := range []int{1, 2, 10001} {
+		ret, err = postJSON("api/task", map[string]any{
+			"id":        id,
+			"date":      stored.Date,
+			"title":     stored.Title,
+			"repeat":    stored.Repeat,
+			"max_count": maxCount,
+		}, http.MethodPut)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], "max_count %d", maxCount)
+	}
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
+
+func TestSkipTask(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	// Пропуск переносит задачу на следующую дату, но не засчитывает выполнение
+	now := time.Now()
+	id := addTask(t, task{
+		date:   now.Format(`20060102`),
+		title:  "Полить цветы",
+		repeat: "d 2",
+	})
+	ret, err := postJSON("api/task/skip?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	stored, err := db.task(id)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 2).Format(`20060102`), stored.Date)
+	assert.Zero(t, stored.DoneCount)
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	// Разовую задачу пропустить нельзя
+	id = addTask(t, task{
+		date:  now.Format(`20060102`),
+		title: "Позвонить",
+	})
+	ret, err = postJSON("api/task/skip?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+	_, err = db.task(id)
+	assert.NoError(t, err)
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
+
 func TestDelTask(t *testing.T) {
 	db := openDB(t)
 	defer db.Close()
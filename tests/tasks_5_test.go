@@ -43,7 +43,7 @@ func TestTasks(t *testing.T) {
 	defer db.Close()
 
 	now := time.Now()
-	_, err := db.Exec("DELETE FROM scheduler")
+	err := db.clear()
 	assert.NoError(t, err)
 
 	tasks := getTasks(t, "")
@@ -106,3 +106,199 @@ func TestTasks(t *testing.T) {
 	assert.Equal(t, len(tasks), 3)
 
 }
+
+func TestRepeatText(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	err := db.clear()
+	assert.NoError(t, err)
+
+	today := time.Now().Format(`20060102`)
+	id := addTask(t, task{
+		date:   today,
+		title:  "Бассейн",
+		repeat: "w 5,1",
+	})
+	addTask(t, task{
+		date:  today,
+		title: "Разовая задача",
+	})
+
+	// Описание правила приходит и в списке задач, и в одной задаче
+	body, err := requestJSON("api/tasks?lang=en", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var list map[string][]map[string]string
+	assert.NoError(t, json.Unmarshal(body, &list))
+	texts := make(map[string]string)
+	for _, v := range list["tasks"] {
+		texts[v["title"]] = v["repeat_text"]
+	}
+	assert.Equal(t, map[string]string{
+		"Бассейн":        "every Monday and Friday",
+		"Разовая задача": "",
+	}, texts)
+
+	body, err = requestJSON("api/task?lang=ru&id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var one map[string]string
+	assert.NoError(t, json.Unmarshal(body, &one))
+	assert.Equal(t, "по понедельникам и пятницам", one["repeat_text"])
+
+	// Описание не сохраняется в базе
+	_, err = postJSON("api/task", map[string]any{
+		"id":          id,
+		"date":        one["date"],
+		"title":       one["title"],
+		"repeat":      "d 7",
+		"repeat_text": "каждую пятницу",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	body, err = requestJSON("api/task?lang=en&id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(body, &one))
+	assert.Equal(t, "every 7 days", one["repeat_text"])
+}
+
+// taskPage — страница списка задач
+type taskPage struct {
+	Tasks []struct {
+		ID    string `json:"id"`
+		Date  string `json:"date"`
+		Title string `json:"title"`
+	} `json:"tasks"`
+	NextCursor string `json:"next_cursor"`
+	Error      string `json:"error"`
+}
+
+func listTasks(t *testing.T, query string) taskPage {
+	body, err := requestJSON("api/tasks?"+query, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var page taskPage
+	assert.NoError(t, json.Unmarshal(body, &page), string(body))
+	return page
+}
+
+// pageTitles возвращает заголовки задач страницы
+func pageTitles(page taskPage) []string {
+	titles := make([]string, 0, len(page.Tasks))
+	for _, task := range page.Tasks {
+		titles = append(titles, task.Title)
+	}
+	return titles
+}
+
+func TestTasksPaging(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	err := db.clear()
+	assert.NoError(t, err)
+
+	now := time.Now()
+	for i, title := range []string{"Д", "Б", "Г", "А", "В"} {
+		addTask(t, task{
+			date:  now.AddDate(0, 0, i+1).Format(`20060102`),
+			title: title,
+		})
+	}
+
+	// Страницы по две задачи, пока не закончится курсор
+	var titles []string
+	var cursors []string
+	query := "sort=title&limit=2"
+	for {
+		page := listTasks(t, query)
+		assert.Empty(t, page.Error)
+		assert.LessOrEqual(t, len(page.Tasks), 2)
+		titles = append(titles, pageTitles(page)...)
+		if page.NextCursor == "" {
+			break
+		}
+		cursors = append(cursors, page.NextCursor)
+		if !assert.Less(t, len(cursors), 5) {
+			break
+		}
+		query = "sort=title&limit=2&cursor=" + page.NextCursor
+	}
+	assert.Equal(t, []string{"А", "Б", "В", "Г", "Д"}, titles)
+	assert.Len(t, cursors, 2)
+
+	// Обратный порядок
+	page := listTasks(t, "sort=-title&limit=3")
+	assert.Equal(t, []string{"Д", "Г", "В"}, pageTitles(page))
+	page = listTasks(t, "sort=-title&limit=3&cursor="+page.NextCursor)
+	assert.Equal(t, []string{"Б", "А"}, pageTitles(page))
+	assert.Empty(t, page.NextCursor)
+
+	// Курсор, который нельзя разобрать или который получен для другой сортировки
+	for _, query := range []string{"cursor=abc", "cursor=" + cursors[0] + "&sort=id"} {
+		page := listTasks(t, query)
+		assert.Equal(t, "Invalid cursor", page.Error, query)
+	}
+	page = listTasks(t, "limit=0")
+	assert.NotEmpty(t, page.Error)
+}
+
+func TestTasksFilter(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	err := db.clear()
+	assert.NoError(t, err)
+
+	now := time.Now().UTC()
+	day := func(days int) string {
+		return now.AddDate(0, 0, days).Format(`20060102`)
+	}
+	// Время 00:00 сегодняшнего дня уже прошло, поэтому задача просрочена
+	ret, err := postJSON("api/task", map[string]any{
+		"date":  day(0),
+		"title": "Привет мир",
+		"time":  "00:00",
+		"tz":    "UTC",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotNil(t, ret["id"])
+	addTask(t, task{date: day(2), title: "Отчёт", comment: "Квартальный ПЛАН", repeat: "d 7"})
+	addTask(t, task{date: day(5), title: "Ёлка", repeat: "y"})
+	addTask(t, task{date: day(10), title: "ab", comment: "Мяч"})
+
+	tbl := []struct {
+		query string
+		want  []string
+	}{
+		// Подстрока без учёта регистра, в том числе для кириллицы
+		{"contains=привет", []string{"Привет мир"}},
+		{"contains=ПРИВЕТ", []string{"Привет мир"}},
+		{"contains=план", []string{"Отчёт"}},
+		{"contains=ёЛ", []string{"Ёлка"}},
+		{"contains=мя", []string{"ab"}},
+		{"contains=AB", []string{"ab"}},
+		{"search=ПРИВЕТ", []string{"Привет мир"}},
+		{"search=мЯ", []string{"ab"}},
+		// Даты включительно
+		{"from=" + day(2) + "&to=" + day(5), []string{"Отчёт", "Ёлка"}},
+		{"from=" + now.AddDate(0, 0, 5).Format("02.01.2006"), []string{"Ёлка", "ab"}},
+		// Повторяющиеся и просроченные
+		{"recurring=true", []string{"Отчёт", "Ёлка"}},
+		{"recurring=false", []string{"Привет мир", "ab"}},
+		{"overdue=true", []string{"Привет мир"}},
+		{"overdue=false", []string{"Отчёт", "Ёлка", "ab"}},
+		{"overdue=true&recurring=true", []string{}},
+		// Сортировка
+		{"sort=title", []string{"ab", "Ёлка", "Отчёт", "Привет мир"}},
+		{"sort=-date", []string{"ab", "Ёлка", "Отчёт", "Привет мир"}},
+		{"sort=-date&recurring=true", []string{"Ёлка", "Отчёт"}},
+	}
+	for _, v := range tbl {
+		page := listTasks(t, v.query)
+		assert.Empty(t, page.Error, v.query)
+		assert.Equal(t, v.want, pageTitles(page), v.query)
+	}
+
+	for _, query := range []string{"from=2024", "to=32.01.2024", "recurring=maybe", "overdue=1x", "sort=comment"} {
+		page := listTasks(t, query)
+		assert.NotEmpty(t, page.Error, query)
+	}
+}